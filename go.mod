@@ -1,6 +1,6 @@
 module grpc-go
 
-go 1.18
+go 1.20
 
 require (
 	google.golang.org/grpc/cmd/protoc-gen-go-grpc v1.3.0 // indirect
@@ -0,0 +1,285 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fullMovie sets every field of Movie.
+var fullMovie = Movie{
+	ID:             7,
+	Title:          "Lagaan",
+	Genre:          "drama",
+	ReleaseDate:    "15-06-2001",
+	Genres:         []string{"drama", "sport"},
+	Directors:      []string{"Ashutosh Gowariker"},
+	Cast:           []CastMember{{Name: "Aamir Khan", Role: "Bhuvan"}, {Name: "Gracy Singh"}},
+	RuntimeMinutes: 224,
+	Rating:         "PG",
+	Languages:      []string{"Hindi", "English"},
+	Country:        "India",
+	Synopsis:       "Villagers stake their taxes on a game of cricket.",
+	PosterURL:      "/posters/7",
+	Version:        3,
+	Source:         "hindi.xml",
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		// roundTrip encodes movies and decodes them again.
+		roundTrip func([]Movie) ([]Movie, error)
+		// keep clears what the format does not carry.
+		keep func(Movie) Movie
+	}{
+		{
+			name: "proto",
+			roundTrip: func(movies []Movie) ([]Movie, error) {
+				var out []Movie
+				for _, m := range movies {
+					out = append(out, FromProto(m.ToProto()))
+				}
+				return out, nil
+			},
+			keep: func(m Movie) Movie { m.Source = ""; return m },
+		},
+		{
+			name: "json",
+			roundTrip: func(movies []Movie) ([]Movie, error) {
+				data, err := json.Marshal(movies)
+				if err != nil {
+					return nil, err
+				}
+				var out []Movie
+				err = json.Unmarshal(data, &out)
+				return out, err
+			},
+			keep: func(m Movie) Movie { m.Source = ""; return m },
+		},
+		{
+			name: "xml",
+			roundTrip: func(movies []Movie) ([]Movie, error) {
+				data, err := xml.Marshal(Movies{Movies: movies})
+				if err != nil {
+					return nil, err
+				}
+				if problems, err := Validate(bytes.NewReader(data), true); err != nil || len(problems) > 0 {
+					t.Errorf("xml: encoded catalog has problems %v, %v", problems, err)
+				}
+				var out Movies
+				err = xml.Unmarshal(data, &out)
+				return out.Movies, err
+			},
+			keep: func(m Movie) Movie {
+				m.ID, m.PosterURL, m.Version, m.Source = 0, "", 0, ""
+				return m
+			},
+		},
+		{
+			name: "csv",
+			roundTrip: func(movies []Movie) ([]Movie, error) {
+				var buf bytes.Buffer
+				w := csv.NewWriter(&buf)
+				w.Write(CSVHeader(Fields))
+				for _, m := range movies {
+					w.Write(CSVRecord(m, Fields))
+				}
+				w.Flush()
+				if err := w.Error(); err != nil {
+					return nil, err
+				}
+				return ReadCSV(&buf)
+			},
+			keep: func(m Movie) Movie {
+				m.ID, m.PosterURL, m.Version, m.Source = 0, "", 0, ""
+				return m
+			},
+		},
+	}
+
+	movies := []Movie{
+		fullMovie,
+		{Title: "Betty-1", Genre: "crime", ReleaseDate: "01-10-2023"},
+	}
+	for _, tt := range tests {
+		got, err := tt.roundTrip(movies)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var want []Movie
+		for _, m := range movies {
+			want = append(want, tt.keep(m))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got\n%+v\nwant\n%+v", tt.name, got, want)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []Movie
+		wantErr string
+	}{
+		{in: "", want: nil},
+		{in: "title,genre\n", want: nil},
+		{
+			in:   "Title,releaseDate,cast\nSholay,15-08-1975,Amitabh Bachchan (Jai); Hema Malini\n",
+			want: []Movie{{Title: "Sholay", ReleaseDate: "15-08-1975", Cast: []CastMember{{Name: "Amitabh Bachchan", Role: "Jai"}, {Name: "Hema Malini"}}}},
+		},
+		{in: "title,budget\nSholay,30000000\n", wantErr: `csv: unknown column "budget"`},
+		{in: "title,runtimeMinutes\nSholay,long\n", wantErr: "csv: line 2: runtimeMinutes"},
+	}
+	for _, tt := range tests {
+		got, err := ReadCSV(strings.NewReader(tt.in))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadCSV(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ReadCSV(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ReadCSV(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Movie
+		want Movie
+	}{
+		{
+			name: "trims text",
+			in:   Movie{Title: " Sholay ", Genre: "action ", ReleaseDate: " 15-08-1975", Country: " India "},
+			want: Movie{Title: "Sholay", Genre: "action", ReleaseDate: "15-08-1975", Country: "India", Genres: []string{"action"}},
+		},
+		{
+			name: "drops empty list entries",
+			in:   Movie{Directors: []string{" Ramesh Sippy ", " "}, Cast: []CastMember{{Name: " "}, {Name: "Sanjeev Kumar ", Role: " Thakur"}}},
+			want: Movie{Directors: []string{"Ramesh Sippy"}, Cast: []CastMember{{Name: "Sanjeev Kumar", Role: "Thakur"}}},
+		},
+		{
+			name: "genre from list",
+			in:   Movie{Genres: []string{"", "crime", "drama"}},
+			want: Movie{Genre: "crime", Genres: []string{"crime", "drama"}},
+		},
+		{
+			name: "genre heads list",
+			in:   Movie{Genre: "drama", Genres: []string{"crime", "drama"}},
+			want: Movie{Genre: "drama", Genres: []string{"drama", "crime"}},
+		},
+	}
+	for _, tt := range tests {
+		got := tt.in
+		got.Normalize()
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMovieValidate(t *testing.T) {
+	tests := []struct {
+		m    Movie
+		want string
+	}{
+		{Movie{Title: "Sholay", ReleaseDate: "15-08-1975"}, ""},
+		{Movie{Title: " ", ReleaseDate: "15-08-1975"}, "missing title"},
+		{Movie{Title: "Sholay", ReleaseDate: "1975-08-15"}, `invalid release date "1975-08-15"`},
+		{Movie{Title: "Sholay", ReleaseDate: "15-08-1975", RuntimeMinutes: -1}, "negative runtime -1"},
+	}
+	for _, tt := range tests {
+		err := tt.m.Validate()
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%+v.Validate() = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		strict bool
+		want   []string
+	}{
+		{
+			name: "valid",
+			doc:  `<movies><movie title="Sholay" genre="action" releaseDate="15-08-1975"><cast><member name="Hema Malini"/></cast></movie></movies>`,
+		},
+		{
+			name: "wrong root",
+			doc:  `<films/>`,
+			want: []string{"1:1: root element is <films>, want <movies>"},
+		},
+		{
+			name: "missing and malformed attributes",
+			doc: `<movies>
+<movie title="Sholay" releaseDate="1975-08-15" runtimeMinutes="0"/>
+</movies>`,
+			want: []string{
+				`2:1: movie "Sholay": missing genre`,
+				`2:1: movie "Sholay": releaseDate "1975-08-15" is not a DD-MM-YYYY date`,
+				`2:1: movie "Sholay": runtimeMinutes "0" is not a positive number`,
+			},
+		},
+		{
+			name: "unknown element below movies",
+			doc:  `<movies><film/></movies>`,
+			want: []string{"1:9: unexpected element <film> in <movies>"},
+		},
+		{
+			name: "unknown attribute ignored when not strict",
+			doc:  `<movies><movie title="Sholay" genre="action" releaseDate="15-08-1975" budget="3"/></movies>`,
+		},
+		{
+			name:   "strict",
+			strict: true,
+			doc: `<movies>
+<movie title="Sholay" genre="action" releaseDate="15-08-1975" budget="3"/>
+<movie title="sholay" genre="action" releaseDate="15-08-1975">text</movie>
+</movies>`,
+			want: []string{
+				"2:1: unknown attribute budget on <movie>",
+				`3:1: movie "sholay": duplicate of the movie on line 2`,
+				"3:63: unexpected text in <movie>",
+			},
+		},
+		{
+			name: "malformed",
+			doc:  "<movies>\n<movie title=\"Sholay\" genre=\"action\" releaseDate=\"15-08-1975\">\n</movies>",
+			want: []string{"3:0: element <movie> closed by </movies>"},
+		},
+	}
+	for _, tt := range tests {
+		problems, err := Validate(strings.NewReader(tt.doc), tt.strict)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, p := range problems {
+			got = append(got, p.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got problems %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// CSVHeader returns the header row for fields.
+func CSVHeader(fields []Field) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// CSVRecord returns the text of fields for m, in order.
+func CSVRecord(m Movie, fields []Field) []string {
+	row := make([]string, len(fields))
+	for i, f := range fields {
+		row[i] = f.Text(m)
+	}
+	return row
+}
+
+// ReadCSV reads movies written with CSVHeader and CSVRecord. The header row
+// names the columns; unknown columns are an error.
+func ReadCSV(r io.Reader) ([]Movie, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]Field, len(header))
+	for i, name := range header {
+		f, ok := FieldByName(name)
+		if !ok {
+			return nil, fmt.Errorf("csv: unknown column %q", name)
+		}
+		fields[i] = f
+	}
+
+	var movies []Movie
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return movies, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var m Movie
+		for i, f := range fields {
+			if err := f.Set(&m, record[i]); err != nil {
+				line, _ := cr.FieldPos(i)
+				return nil, fmt.Errorf("csv: line %d: %w", line, err)
+			}
+		}
+		movies = append(movies, m)
+	}
+}
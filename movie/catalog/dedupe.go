@@ -0,0 +1,158 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+
+	"movie/search"
+)
+
+// DefaultDuplicateThreshold is the title similarity at which two movies
+// released on the same day are taken to be the same movie. It is high
+// enough to keep numbered titles such as "Betty-1" and "Betty-2" apart.
+const DefaultDuplicateThreshold = 0.9
+
+// LikelyDuplicates reports whether a and b probably describe the same
+// movie: they have the same release date, share a genre unless either has
+// none, and their titles score at least threshold with
+// search.TitleSimilarity.
+func LikelyDuplicates(a, b Movie, threshold float64) bool {
+	if strings.TrimSpace(a.ReleaseDate) != strings.TrimSpace(b.ReleaseDate) {
+		return false
+	}
+	if !shareGenre(a, b) {
+		return false
+	}
+	return search.TitleSimilarity(a.Title, b.Title) >= threshold
+}
+
+func allGenres(m Movie) []string {
+	genres := append([]string{m.Genre}, m.Genres...)
+	return trimList(genres)
+}
+
+func shareGenre(a, b Movie) bool {
+	ga, gb := allGenres(a), allGenres(b)
+	if len(ga) == 0 || len(gb) == 0 {
+		return true
+	}
+	for _, x := range ga {
+		for _, y := range gb {
+			if strings.EqualFold(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindDuplicates groups the movies that are likely duplicates of each
+// other, directly or through another movie of the group. Each group holds
+// at least two indexes into movies, in order; groups are ordered by their
+// first movie.
+func FindDuplicates(movies []Movie, threshold float64) [][]int {
+	parent := make([]int, len(movies))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// Only movies with the same release date can be duplicates.
+	byDate := map[string][]int{}
+	for i, m := range movies {
+		d := strings.TrimSpace(m.ReleaseDate)
+		byDate[d] = append(byDate[d], i)
+	}
+	for _, idx := range byDate {
+		for x := 0; x < len(idx); x++ {
+			for y := x + 1; y < len(idx); y++ {
+				i, j := idx[x], idx[y]
+				if find(i) != find(j) && LikelyDuplicates(movies[i], movies[j], threshold) {
+					parent[find(j)] = find(i)
+				}
+			}
+		}
+	}
+
+	members := map[int][]int{}
+	for i := range movies {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+	var groups [][]int
+	for _, g := range members {
+		if len(g) > 1 {
+			groups = append(groups, g)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
+
+// Merge combines survivor with its duplicates. The survivor keeps its ID,
+// poster and every field it has; empty scalar fields are taken from the
+// first duplicate that has them, and lists are joined without repeats,
+// ignoring case. The result is normalized.
+func Merge(survivor Movie, duplicates ...Movie) Movie {
+	m := survivor
+	m.Cast = append([]CastMember(nil), survivor.Cast...)
+	for _, d := range duplicates {
+		for _, f := range []struct{ dst, src *string }{
+			{&m.Title, &d.Title},
+			{&m.Genre, &d.Genre},
+			{&m.ReleaseDate, &d.ReleaseDate},
+			{&m.Rating, &d.Rating},
+			{&m.Country, &d.Country},
+			{&m.Synopsis, &d.Synopsis},
+		} {
+			if strings.TrimSpace(*f.dst) == "" {
+				*f.dst = *f.src
+			}
+		}
+		if m.RuntimeMinutes == 0 {
+			m.RuntimeMinutes = d.RuntimeMinutes
+		}
+		m.Genres = union(allGenres(m), allGenres(d))
+		m.Directors = union(m.Directors, d.Directors)
+		m.Languages = union(m.Languages, d.Languages)
+		for _, c := range d.Cast {
+			if !hasCastMember(m.Cast, c.Name) {
+				m.Cast = append(m.Cast, c)
+			}
+		}
+	}
+	m.Normalize()
+	return m
+}
+
+func union(a, b []string) []string {
+	out := append([]string(nil), a...)
+	for _, v := range b {
+		found := false
+		for _, w := range out {
+			if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(w)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func hasCastMember(cast []CastMember, name string) bool {
+	for _, c := range cast {
+		if strings.EqualFold(strings.TrimSpace(c.Name), strings.TrimSpace(name)) {
+			return true
+		}
+	}
+	return false
+}
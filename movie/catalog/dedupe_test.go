@@ -0,0 +1,123 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLikelyDuplicates(t *testing.T) {
+	tests := []struct {
+		a, b Movie
+		want bool
+	}{
+		{
+			Movie{Title: "Sholay", Genre: "action", ReleaseDate: "15-08-1975"},
+			Movie{Title: "sholay!", Genre: "Action", ReleaseDate: " 15-08-1975"},
+			true,
+		},
+		{
+			Movie{Title: "Deewaar", Genre: "crime", ReleaseDate: "24-01-1975"},
+			Movie{Title: "Deewaar.", ReleaseDate: "24-01-1975"},
+			true,
+		},
+		{
+			Movie{Title: "Deewaar", Genre: "crime", ReleaseDate: "24-01-1975"},
+			Movie{Title: "Deewar", Genre: "crime", ReleaseDate: "24-01-1975"},
+			false,
+		},
+		{
+			Movie{Title: "Sholay", Genre: "action", ReleaseDate: "15-08-1975"},
+			Movie{Title: "Sholay", Genre: "action", ReleaseDate: "15-08-2015"},
+			false,
+		},
+		{
+			Movie{Title: "Sholay", Genre: "action", ReleaseDate: "15-08-1975"},
+			Movie{Title: "Sholay", Genres: []string{"drama"}, ReleaseDate: "15-08-1975"},
+			false,
+		},
+		{
+			Movie{Title: "Betty-1", Genre: "crime", ReleaseDate: "01-10-2023"},
+			Movie{Title: "Betty-2", Genre: "crime", ReleaseDate: "01-10-2023"},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		if got := LikelyDuplicates(tt.a, tt.b, DefaultDuplicateThreshold); got != tt.want {
+			t.Errorf("LikelyDuplicates(%q, %q) = %v, want %v", tt.a.Title, tt.b.Title, got, tt.want)
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	movies := []Movie{
+		{Title: "Lagaan", ReleaseDate: "15-06-2001"},
+		{Title: "Sholay", ReleaseDate: "15-08-1975"},
+		{Title: "Deewaar", ReleaseDate: "24-01-1975"},
+		{Title: "Lagaan: Once Upon a Time in India", ReleaseDate: "15-06-2001"},
+		{Title: "SHOLAY", ReleaseDate: "15-08-1975"},
+		{Title: "Deewar", ReleaseDate: "24-01-1975"},
+		{Title: "Sholay.", ReleaseDate: "15-08-1975"},
+		{Title: "Betty-1", ReleaseDate: "01-10-2023"},
+		{Title: "Betty-2", ReleaseDate: "01-10-2023"},
+	}
+	tests := []struct {
+		threshold float64
+		want      [][]int
+	}{
+		{DefaultDuplicateThreshold, [][]int{{1, 4, 6}}},
+		{0.8, [][]int{{1, 4, 6}, {2, 5}, {7, 8}}},
+		{1, [][]int{{1, 4, 6}}},
+	}
+	for _, tt := range tests {
+		if got := FindDuplicates(movies, tt.threshold); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindDuplicates at %v = %v, want %v", tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	survivor := Movie{
+		ID:          1,
+		Title:       "Sholay",
+		Genre:       "action",
+		ReleaseDate: "15-08-1975",
+		Directors:   []string{"Ramesh Sippy"},
+		Cast:        []CastMember{{Name: "Amitabh Bachchan", Role: "Jai"}},
+		PosterURL:   "/posters/1",
+		Version:     4,
+	}
+	duplicates := []Movie{
+		{
+			ID:             5,
+			Title:          "SHOLAY",
+			Genres:         []string{"Action", "drama"},
+			ReleaseDate:    "15-08-1975",
+			Directors:      []string{"ramesh sippy "},
+			Cast:           []CastMember{{Name: "amitabh bachchan"}, {Name: "Hema Malini", Role: "Basanti"}},
+			RuntimeMinutes: 204,
+			Country:        "India",
+			PosterURL:      "/posters/5",
+		},
+		{ID: 7, Title: "Sholay", RuntimeMinutes: 198, Country: "Indien", Synopsis: "Two convicts hunt a dacoit."},
+	}
+	want := Movie{
+		ID:             1,
+		Title:          "Sholay",
+		Genre:          "action",
+		Genres:         []string{"action", "drama"},
+		ReleaseDate:    "15-08-1975",
+		Directors:      []string{"Ramesh Sippy"},
+		Cast:           []CastMember{{Name: "Amitabh Bachchan", Role: "Jai"}, {Name: "Hema Malini", Role: "Basanti"}},
+		RuntimeMinutes: 204,
+		Country:        "India",
+		Synopsis:       "Two convicts hunt a dacoit.",
+		PosterURL:      "/posters/1",
+		Version:        4,
+	}
+	if got := Merge(survivor, duplicates...); !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%+v\nwant\n%+v", got, want)
+	}
+	if survivor.Genres != nil || len(survivor.Cast) != 1 {
+		t.Errorf("survivor changed to %+v", survivor)
+	}
+}
@@ -0,0 +1,107 @@
+package catalog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldChange is a field whose value differs between two versions of a
+// movie, in the text form of Field.Text.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Changes returns the Fields that differ between a and b.
+func Changes(a, b Movie) []FieldChange {
+	var changes []FieldChange
+	for _, f := range Fields {
+		if ov, nv := f.Text(a), f.Text(b); ov != nv {
+			changes = append(changes, FieldChange{f.Name, ov, nv})
+		}
+	}
+	return changes
+}
+
+// ChangedMovie is a movie present in both lists of a Diff.
+type ChangedMovie struct {
+	Before, After Movie
+	Changes       []FieldChange
+}
+
+// Diff is the difference between two lists of movies.
+type Diff struct {
+	Added     []Movie
+	Removed   []Movie
+	Changed   []ChangedMovie
+	Unchanged int
+}
+
+// Empty reports whether the lists hold the same movies.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// MatchMovies pairs the movies of two lists by title, ignoring case and
+// surrounding spaces. Repeated titles are paired in the order they appear
+// in each list. It returns, for every movie of newMovies, the index of its
+// match in oldMovies, or -1.
+func MatchMovies(oldMovies, newMovies []Movie) []int {
+	key := func(m Movie, seen map[string]int) string {
+		t := strings.ToLower(strings.TrimSpace(m.Title))
+		seen[t]++
+		return fmt.Sprintf("%s#%d", t, seen[t])
+	}
+
+	seen := map[string]int{}
+	oldByKey := make(map[string]int, len(oldMovies))
+	for i, m := range oldMovies {
+		oldByKey[key(m, seen)] = i
+	}
+	seen = map[string]int{}
+	match := make([]int, len(newMovies))
+	for i, m := range newMovies {
+		j, ok := oldByKey[key(m, seen)]
+		if !ok {
+			j = -1
+		}
+		match[i] = j
+	}
+	return match
+}
+
+// DiffMovies compares two lists of movies, pairing them as MatchMovies
+// does. The result follows the order of the lists.
+func DiffMovies(oldMovies, newMovies []Movie) Diff {
+	match := MatchMovies(oldMovies, newMovies)
+	matchedBy := make([]int, len(oldMovies))
+	for j := range matchedBy {
+		matchedBy[j] = -1
+	}
+	for i, j := range match {
+		if j >= 0 {
+			matchedBy[j] = i
+		}
+	}
+
+	var d Diff
+	for j, o := range oldMovies {
+		if matchedBy[j] < 0 {
+			d.Removed = append(d.Removed, o)
+			continue
+		}
+		n := newMovies[matchedBy[j]]
+		if changes := Changes(o, n); len(changes) > 0 {
+			d.Changed = append(d.Changed, ChangedMovie{o, n, changes})
+		} else {
+			d.Unchanged++
+		}
+	}
+	for i, n := range newMovies {
+		if match[i] < 0 {
+			d.Added = append(d.Added, n)
+		}
+	}
+	return d
+}
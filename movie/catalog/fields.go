@@ -0,0 +1,135 @@
+package catalog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// listSep separates list entries in the text form of a field.
+const listSep = "; "
+
+// Field is a named column of Movie, used for CSV and other tabular forms.
+// Set parses the text form written by Text; an empty string clears the
+// field. Copy sets the field of dst to its value in src.
+type Field struct {
+	Name string
+	Get  func(Movie) interface{} // string, int32 or []string
+	Set  func(*Movie, string) error
+	Copy func(dst *Movie, src Movie)
+}
+
+// Text renders the value of f for m as a single string.
+func (f Field) Text(m Movie) string {
+	return FormatValue(f.Get(m))
+}
+
+// FormatValue renders a field value as a single string. A zero number is
+// empty.
+func FormatValue(v interface{}) string {
+	switch v := v.(type) {
+	case []string:
+		return strings.Join(v, listSep)
+	case int32:
+		if v == 0 {
+			return ""
+		}
+		return strconv.Itoa(int(v))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func splitList(s string) []string {
+	return trimList(strings.Split(s, strings.TrimSpace(listSep)))
+}
+
+func stringField(name string, p func(*Movie) *string) Field {
+	return Field{
+		Name: name,
+		Get:  func(m Movie) interface{} { return *p(&m) },
+		Set:  func(m *Movie, s string) error { *p(m) = s; return nil },
+		Copy: func(dst *Movie, src Movie) { *p(dst) = *p(&src) },
+	}
+}
+
+func listField(name string, p func(*Movie) *[]string) Field {
+	return Field{
+		Name: name,
+		Get:  func(m Movie) interface{} { return *p(&m) },
+		Set:  func(m *Movie, s string) error { *p(m) = splitList(s); return nil },
+		Copy: func(dst *Movie, src Movie) { *p(dst) = append([]string(nil), *p(&src)...) },
+	}
+}
+
+// Fields are the columns of a movie in their canonical order. ID, poster
+// URL and source are not part of it.
+var Fields = []Field{
+	stringField("title", func(m *Movie) *string { return &m.Title }),
+	stringField("genre", func(m *Movie) *string { return &m.Genre }),
+	stringField("releaseDate", func(m *Movie) *string { return &m.ReleaseDate }),
+	listField("genres", func(m *Movie) *[]string { return &m.Genres }),
+	listField("directors", func(m *Movie) *[]string { return &m.Directors }),
+	{
+		Name: "cast",
+		Get:  castValue,
+		Set:  setCast,
+		Copy: func(dst *Movie, src Movie) { dst.Cast = append([]CastMember(nil), src.Cast...) },
+	},
+	{
+		Name: "runtimeMinutes",
+		Get:  func(m Movie) interface{} { return m.RuntimeMinutes },
+		Set: func(m *Movie, s string) error {
+			if s = strings.TrimSpace(s); s == "" {
+				m.RuntimeMinutes = 0
+				return nil
+			}
+			n, err := strconv.ParseInt(s, 10, 32)
+			if err != nil {
+				return fmt.Errorf("runtimeMinutes: %w", err)
+			}
+			m.RuntimeMinutes = int32(n)
+			return nil
+		},
+		Copy: func(dst *Movie, src Movie) { dst.RuntimeMinutes = src.RuntimeMinutes },
+	},
+	stringField("rating", func(m *Movie) *string { return &m.Rating }),
+	listField("languages", func(m *Movie) *[]string { return &m.Languages }),
+	stringField("country", func(m *Movie) *string { return &m.Country }),
+	stringField("synopsis", func(m *Movie) *string { return &m.Synopsis }),
+}
+
+// FieldByName returns the field called name, ignoring case.
+func FieldByName(name string) (Field, bool) {
+	for _, f := range Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// castValue lists cast members as "Name (Role)".
+func castValue(m Movie) interface{} {
+	var cast []string
+	for _, c := range m.Cast {
+		if c.Role != "" {
+			cast = append(cast, fmt.Sprintf("%s (%s)", c.Name, c.Role))
+		} else {
+			cast = append(cast, c.Name)
+		}
+	}
+	return cast
+}
+
+func setCast(m *Movie, s string) error {
+	m.Cast = nil
+	for _, v := range splitList(s) {
+		c := CastMember{Name: v}
+		if i := strings.LastIndex(v, " ("); i > 0 && strings.HasSuffix(v, ")") {
+			c = CastMember{Name: v[:i], Role: v[i+2 : len(v)-1]}
+		}
+		m.Cast = append(m.Cast, c)
+	}
+	return nil
+}
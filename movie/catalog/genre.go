@@ -0,0 +1,266 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Genre is an entry of the genre taxonomy. Movies refer to genres by ID.
+type Genre struct {
+	ID      string            `json:"id"`
+	Parent  string            `json:"parent,omitempty"`
+	Aliases []string          `json:"aliases,omitempty"`
+	Names   map[string]string `json:"names,omitempty"` // display names by language
+}
+
+// DisplayName returns the name of g in lang, falling back to English and
+// then to the ID.
+func (g Genre) DisplayName(lang string) string {
+	if n := g.Names[lang]; n != "" {
+		return n
+	}
+	if n := g.Names["en"]; n != "" {
+		return n
+	}
+	return g.ID
+}
+
+// DefaultGenres is the taxonomy used until one has been configured.
+var DefaultGenres = []Genre{
+	{ID: "action", Names: map[string]string{"en": "Action", "hi": "एक्शन"}},
+	{ID: "animation", Aliases: []string{"animated", "cartoon"}, Names: map[string]string{"en": "Animation", "hi": "एनिमेशन"}},
+	{ID: "comedy", Aliases: []string{"comic"}, Names: map[string]string{"en": "Comedy", "hi": "हास्य"}},
+	{ID: "crime", Names: map[string]string{"en": "Crime", "hi": "अपराध"}},
+	{ID: "crime-thriller", Parent: "thriller", Names: map[string]string{"en": "Crime thriller", "hi": "अपराध थ्रिलर"}},
+	{ID: "documentary", Aliases: []string{"doc"}, Names: map[string]string{"en": "Documentary", "hi": "वृत्तचित्र"}},
+	{ID: "drama", Names: map[string]string{"en": "Drama", "hi": "नाटक"}},
+	{ID: "horror", Names: map[string]string{"en": "Horror", "hi": "डरावनी"}},
+	{ID: "musical", Aliases: []string{"music"}, Names: map[string]string{"en": "Musical", "hi": "संगीतमय"}},
+	{ID: "romance", Aliases: []string{"romantic"}, Names: map[string]string{"en": "Romance", "hi": "रोमांस"}},
+	{ID: "sci-fi", Aliases: []string{"science fiction", "sf"}, Names: map[string]string{"en": "Science fiction", "hi": "विज्ञान कथा"}},
+	{ID: "sport", Aliases: []string{"sports"}, Names: map[string]string{"en": "Sport", "hi": "खेल"}},
+	{ID: "thriller", Aliases: []string{"suspense"}, Names: map[string]string{"en": "Thriller", "hi": "थ्रिलर"}},
+}
+
+// foldGenre reduces a genre name to its lower-case letters and digits, so
+// "Sci-Fi", "sci fi" and "scifi" compare equal.
+func foldGenre(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Taxonomy resolves genre names to canonical IDs. It is immutable; Upsert
+// returns a new Taxonomy.
+type Taxonomy struct {
+	genres map[string]Genre
+	names  map[string]string // folded ID, alias or display name to ID
+}
+
+// NewTaxonomy checks genres and builds a Taxonomy from them. IDs must be
+// unique and non-empty, parents must exist without forming a cycle, and no
+// two genres may share an alias or display name.
+func NewTaxonomy(genres []Genre) (*Taxonomy, error) {
+	t := &Taxonomy{genres: map[string]Genre{}, names: map[string]string{}}
+	for _, g := range genres {
+		if g.ID == "" || foldGenre(g.ID) == "" {
+			return nil, fmt.Errorf("genre %q: invalid id", g.ID)
+		}
+		if _, ok := t.genres[g.ID]; ok {
+			return nil, fmt.Errorf("genre %q: duplicate id", g.ID)
+		}
+		t.genres[g.ID] = g
+	}
+
+	// IDs win over aliases and display names of other genres.
+	for id := range t.genres {
+		if other, ok := t.names[foldGenre(id)]; ok {
+			return nil, fmt.Errorf("genre %q: id clashes with %q", id, other)
+		}
+		t.names[foldGenre(id)] = id
+	}
+	for _, g := range genres {
+		for _, name := range append(append([]string{}, g.Aliases...), displayNames(g)...) {
+			key := foldGenre(name)
+			if key == "" {
+				continue
+			}
+			if other, ok := t.names[key]; ok && other != g.ID {
+				return nil, fmt.Errorf("genre %q: name %q is already used by %q", g.ID, name, other)
+			}
+			t.names[key] = g.ID
+		}
+	}
+
+	for id, g := range t.genres {
+		seen := map[string]bool{id: true}
+		for p := g.Parent; p != ""; p = t.genres[p].Parent {
+			if _, ok := t.genres[p]; !ok {
+				return nil, fmt.Errorf("genre %q: unknown parent %q", id, p)
+			}
+			if seen[p] {
+				return nil, fmt.Errorf("genre %q: parent cycle through %q", id, p)
+			}
+			seen[p] = true
+		}
+	}
+	return t, nil
+}
+
+func displayNames(g Genre) []string {
+	var names []string
+	for _, n := range g.Names {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Canonical returns the ID of the genre called name, by ID, alias or
+// display name.
+func (t *Taxonomy) Canonical(name string) (string, bool) {
+	id, ok := t.names[foldGenre(name)]
+	return id, ok
+}
+
+// DefaultTaxonomy returns the taxonomy of DefaultGenres.
+func DefaultTaxonomy() *Taxonomy {
+	t, err := NewTaxonomy(DefaultGenres)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// key returns the ID of the genre called name, or the folded name when it
+// is not in the taxonomy.
+func (t *Taxonomy) key(name string) string {
+	folded := foldGenre(name)
+	if id, ok := t.names[folded]; ok {
+		return id
+	}
+	return folded
+}
+
+// HasGenre reports whether m has genre or a genre below it. Names are
+// resolved through the taxonomy, so aliases match; unknown names compare
+// ignoring case, spaces and punctuation.
+func (t *Taxonomy) HasGenre(m Movie, genre string) bool {
+	want := map[string]bool{t.key(genre): true}
+	if id, ok := t.Canonical(genre); ok {
+		for _, d := range t.Descendants(id) {
+			want[d] = true
+		}
+	}
+	if m.Genre != "" && want[t.key(m.Genre)] {
+		return true
+	}
+	for _, g := range m.Genres {
+		if want[t.key(g)] {
+			return true
+		}
+	}
+	return false
+}
+
+// Genres returns every genre, sorted by ID.
+func (t *Taxonomy) Genres() []Genre {
+	genres := make([]Genre, 0, len(t.genres))
+	for _, g := range t.genres {
+		genres = append(genres, g)
+	}
+	sort.Slice(genres, func(i, j int) bool { return genres[i].ID < genres[j].ID })
+	return genres
+}
+
+// Genre returns the genre with id.
+func (t *Taxonomy) Genre(id string) (Genre, bool) {
+	g, ok := t.genres[id]
+	return g, ok
+}
+
+// Descendants returns id and the IDs of every genre below it.
+func (t *Taxonomy) Descendants(id string) []string {
+	ids := []string{id}
+	for _, g := range t.Genres() {
+		for p := g.Parent; p != ""; p = t.genres[p].Parent {
+			if p == id {
+				ids = append(ids, g.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// Upsert returns a taxonomy with g added, or replacing the genre with the
+// same ID.
+func (t *Taxonomy) Upsert(g Genre) (*Taxonomy, error) {
+	genres := []Genre{g}
+	for _, old := range t.Genres() {
+		if old.ID != g.ID {
+			genres = append(genres, old)
+		}
+	}
+	return NewTaxonomy(genres)
+}
+
+// UnknownGenresError lists the genres of a movie that are not in the
+// taxonomy.
+type UnknownGenresError struct {
+	Genres []string
+}
+
+func (e *UnknownGenresError) Error() string {
+	return fmt.Sprintf("unknown genre(s): %s", strings.Join(e.Genres, ", "))
+}
+
+// NormalizeGenres replaces the genres of m by their canonical IDs and
+// removes repeats. Unknown genres are kept as they are, unless strict is
+// set, in which case m is left unchanged and an *UnknownGenresError is
+// returned.
+func (t *Taxonomy) NormalizeGenres(m *Movie, strict bool) error {
+	var unknown []string
+	resolve := func(name string) string {
+		if id, ok := t.Canonical(name); ok {
+			return id
+		}
+		for _, u := range unknown {
+			if u == name {
+				return name
+			}
+		}
+		unknown = append(unknown, name)
+		return name
+	}
+
+	genre := m.Genre
+	if genre != "" {
+		genre = resolve(genre)
+	}
+	var genres []string
+	seen := map[string]bool{genre: true}
+	for _, g := range m.Genres {
+		id := resolve(g)
+		if !seen[id] {
+			seen[id] = true
+			genres = append(genres, id)
+		}
+	}
+	if strict && len(unknown) > 0 {
+		return &UnknownGenresError{Genres: unknown}
+	}
+
+	if genre != "" {
+		genres = append([]string{genre}, genres...)
+	}
+	m.Genre, m.Genres = genre, genres
+	m.Normalize()
+	return nil
+}
@@ -0,0 +1,173 @@
+package catalog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	tax := DefaultTaxonomy()
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"sci-fi", "sci-fi", true},
+		{"Sci Fi", "sci-fi", true},
+		{"SCIFI", "sci-fi", true},
+		{"Science-Fiction", "sci-fi", true},
+		{"sf", "sci-fi", true},
+		{"Sports", "sport", true},
+		{"Crime thriller", "crime-thriller", true},
+		{"अपराध", "crime", true},
+		{" suspense ", "thriller", true},
+		{"western", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := tax.Canonical(tt.name)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Canonical(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNewTaxonomy(t *testing.T) {
+	tests := []struct {
+		name    string
+		genres  []Genre
+		wantErr string
+	}{
+		{name: "default", genres: DefaultGenres},
+		{name: "empty id", genres: []Genre{{ID: "-"}}, wantErr: `genre "-": invalid id`},
+		{name: "duplicate id", genres: []Genre{{ID: "drama"}, {ID: "drama"}}, wantErr: `genre "drama": duplicate id`},
+		{
+			name:    "id folds like another",
+			genres:  []Genre{{ID: "sci-fi"}, {ID: "scifi"}},
+			wantErr: "clashes with",
+		},
+		{
+			name:    "alias of two genres",
+			genres:  []Genre{{ID: "drama", Aliases: []string{"serious"}}, {ID: "crime", Aliases: []string{"Serious"}}},
+			wantErr: `genre "crime": name "Serious" is already used by "drama"`,
+		},
+		{
+			name:    "alias is another id",
+			genres:  []Genre{{ID: "drama"}, {ID: "crime", Aliases: []string{"drama"}}},
+			wantErr: `genre "crime": name "drama" is already used by "drama"`,
+		},
+		{name: "unknown parent", genres: []Genre{{ID: "noir", Parent: "crime"}}, wantErr: `genre "noir": unknown parent "crime"`},
+		{
+			name:    "parent cycle",
+			genres:  []Genre{{ID: "a", Parent: "b"}, {ID: "b", Parent: "a"}},
+			wantErr: "parent cycle",
+		},
+	}
+	for _, tt := range tests {
+		_, err := NewTaxonomy(tt.genres)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHasGenre(t *testing.T) {
+	tax, err := NewTaxonomy(append([]Genre{{ID: "heist", Parent: "crime-thriller", Aliases: []string{"caper"}}}, DefaultGenres...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		m     Movie
+		genre string
+		want  bool
+	}{
+		{Movie{Genre: "sci-fi"}, "Science Fiction", true},
+		{Movie{Genre: "Science Fiction"}, "sci-fi", true},
+		{Movie{Genre: "drama", Genres: []string{"drama", "sports"}}, "sport", true},
+		{Movie{Genre: "crime-thriller"}, "thriller", true},
+		{Movie{Genre: "caper"}, "suspense", true},
+		{Movie{Genre: "heist"}, "crime-thriller", true},
+		{Movie{Genre: "thriller"}, "crime-thriller", false},
+		{Movie{Genre: "crime"}, "crime-thriller", false},
+		{Movie{Genre: "Film Noir"}, "film-noir", true},
+		{Movie{Genre: "film noir"}, "noir", false},
+	}
+	for _, tt := range tests {
+		if got := tax.HasGenre(tt.m, tt.genre); got != tt.want {
+			t.Errorf("HasGenre(%q/%q, %q) = %v, want %v", tt.m.Genre, tt.m.Genres, tt.genre, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeGenres(t *testing.T) {
+	tax := DefaultTaxonomy()
+	tests := []struct {
+		name        string
+		in          Movie
+		strict      bool
+		want        Movie
+		wantUnknown []string
+	}{
+		{
+			name: "aliases and repeats",
+			in:   Movie{Genre: "Science Fiction", Genres: []string{"sf", "Sports", "sport"}},
+			want: Movie{Genre: "sci-fi", Genres: []string{"sci-fi", "sport"}},
+		},
+		{
+			name: "unknown kept",
+			in:   Movie{Genre: "Western", Genres: []string{"drama"}},
+			want: Movie{Genre: "Western", Genres: []string{"Western", "drama"}},
+		},
+		{
+			name:        "unknown rejected when strict",
+			in:          Movie{Genre: "Western", Genres: []string{"comic", "Western", "noir"}},
+			strict:      true,
+			want:        Movie{Genre: "Western", Genres: []string{"comic", "Western", "noir"}},
+			wantUnknown: []string{"Western", "noir"},
+		},
+		{
+			name:   "genre from list",
+			in:     Movie{Genres: []string{"suspense", "animated"}},
+			strict: true,
+			want:   Movie{Genre: "thriller", Genres: []string{"thriller", "animation"}},
+		},
+	}
+	for _, tt := range tests {
+		got := tt.in
+		err := tax.NormalizeGenres(&got, tt.strict)
+		var unknown []string
+		if e, ok := err.(*UnknownGenresError); ok {
+			unknown = e.Genres
+		} else if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(unknown, tt.wantUnknown) {
+			t.Errorf("%s: unknown genres %q, want %q", tt.name, unknown, tt.wantUnknown)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenreProto(t *testing.T) {
+	g := Genre{ID: "sci-fi", Parent: "fiction", Aliases: []string{"sf"}, Names: map[string]string{"en": "Science fiction", "hi": "विज्ञान कथा"}}
+	p := g.ToProto("hi")
+	if p.GetDisplayName() != "विज्ञान कथा" {
+		t.Errorf("display name %q, want the Hindi name", p.GetDisplayName())
+	}
+	if got := GenreFromProto(p); !reflect.DeepEqual(got, g) {
+		t.Errorf("got %+v, want %+v", got, g)
+	}
+	if got := g.ToProto("fr").GetDisplayName(); got != "Science fiction" {
+		t.Errorf("display name in French %q, want the English one", got)
+	}
+}
@@ -0,0 +1,115 @@
+// Package catalog is the movie domain shared by u1, the gateway and the
+// server: the Movie type with its normalization and validation, its XML,
+// JSON, CSV and protobuf forms, the genre taxonomy, and the diffing and
+// duplicate detection of movie lists.
+package catalog
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Movie is a movie of the library. The same type is stored by the server as
+// JSON, exchanged as XML catalogs and converted to pb.Movie for the gRPC
+// API. Scalar fields are attributes of <movie>; lists and the synopsis are
+// child elements, so documents with only the original attributes still
+// decode.
+type Movie struct {
+	ID             int32        `json:"id" xml:"-"`
+	Title          string       `json:"title" xml:"title,attr"`
+	Genre          string       `json:"genre" xml:"genre,attr"`
+	ReleaseDate    string       `json:"releaseDate" xml:"releaseDate,attr"`
+	Genres         []string     `json:"genres,omitempty" xml:"genres>genre"`
+	Directors      []string     `json:"directors,omitempty" xml:"directors>director"`
+	Cast           []CastMember `json:"cast,omitempty" xml:"cast>member"`
+	RuntimeMinutes int32        `json:"runtimeMinutes,omitempty" xml:"runtimeMinutes,attr,omitempty"`
+	Rating         string       `json:"rating,omitempty" xml:"rating,attr,omitempty"`
+	Languages      []string     `json:"languages,omitempty" xml:"languages>language"`
+	Country        string       `json:"country,omitempty" xml:"country,attr,omitempty"`
+	Synopsis       string       `json:"synopsis,omitempty" xml:"synopsis,omitempty"`
+	PosterURL      string       `json:"posterUrl,omitempty" xml:"-"`
+	Version        int64        `json:"version,omitempty" xml:"-"` // bumped by the server on every change
+
+	// Source is where the movie was read from, e.g. a file name. It is
+	// never stored.
+	Source string `json:"-" xml:"-"`
+}
+
+// CastMember is an actor and the role they play.
+type CastMember struct {
+	Name string `json:"name" xml:"name,attr"`
+	Role string `json:"role,omitempty" xml:"role,attr,omitempty"`
+}
+
+// Movies is an XML catalog document.
+type Movies struct {
+	XMLName xml.Name `xml:"movies"`
+	Movies  []Movie  `xml:"movie"`
+}
+
+// Released parses the release date of m.
+func (m Movie) Released() (time.Time, error) {
+	return time.Parse(ReleaseDateLayout, strings.TrimSpace(m.ReleaseDate))
+}
+
+// Normalize trims the text fields of m, drops empty list entries and keeps
+// the primary genre and the genre list in step: the primary genre defaults
+// to the first listed one and always heads the list.
+func (m *Movie) Normalize() {
+	for _, s := range []*string{&m.Title, &m.Genre, &m.ReleaseDate, &m.Rating, &m.Country, &m.Synopsis} {
+		*s = strings.TrimSpace(*s)
+	}
+	m.Genres = trimList(m.Genres)
+	m.Directors = trimList(m.Directors)
+	m.Languages = trimList(m.Languages)
+	var cast []CastMember
+	for _, c := range m.Cast {
+		c.Name, c.Role = strings.TrimSpace(c.Name), strings.TrimSpace(c.Role)
+		if c.Name != "" {
+			cast = append(cast, c)
+		}
+	}
+	m.Cast = cast
+
+	if m.Genre == "" {
+		if len(m.Genres) > 0 {
+			m.Genre = m.Genres[0]
+		}
+		return
+	}
+	genres := []string{m.Genre}
+	for _, g := range m.Genres {
+		if g != m.Genre {
+			genres = append(genres, g)
+		}
+	}
+	m.Genres = genres
+}
+
+func trimList(list []string) []string {
+	var out []string
+	for _, v := range list {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Validate reports the first problem of m: a missing title, a release date
+// not in DD-MM-YYYY form or a negative runtime.
+func (m Movie) Validate() error {
+	if strings.TrimSpace(m.Title) == "" {
+		return errors.New("missing title")
+	}
+	if _, err := m.Released(); err != nil {
+		return fmt.Errorf("invalid release date %q", m.ReleaseDate)
+	}
+	if m.RuntimeMinutes < 0 {
+		return fmt.Errorf("negative runtime %d", m.RuntimeMinutes)
+	}
+	return nil
+}
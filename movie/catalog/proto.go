@@ -0,0 +1,72 @@
+package catalog
+
+import pb "movie/proto"
+
+// ToProto converts m to its gRPC form.
+func (m Movie) ToProto() *pb.Movie {
+	cast := make([]*pb.CastMember, len(m.Cast))
+	for i, c := range m.Cast {
+		cast[i] = &pb.CastMember{Name: c.Name, Role: c.Role}
+	}
+	return &pb.Movie{
+		Id:             m.ID,
+		Title:          m.Title,
+		Genre:          m.Genre,
+		ReleaseDate:    m.ReleaseDate,
+		Genres:         m.Genres,
+		Directors:      m.Directors,
+		Cast:           cast,
+		RuntimeMinutes: m.RuntimeMinutes,
+		Rating:         m.Rating,
+		Languages:      m.Languages,
+		Country:        m.Country,
+		Synopsis:       m.Synopsis,
+		PosterUrl:      m.PosterURL,
+		Version:        m.Version,
+	}
+}
+
+// FromProto converts a gRPC movie. It does not normalize the result.
+func FromProto(p *pb.Movie) Movie {
+	var cast []CastMember
+	for _, c := range p.GetCast() {
+		cast = append(cast, CastMember{Name: c.GetName(), Role: c.GetRole()})
+	}
+	return Movie{
+		ID:             p.GetId(),
+		Title:          p.GetTitle(),
+		Genre:          p.GetGenre(),
+		ReleaseDate:    p.GetReleaseDate(),
+		Genres:         p.GetGenres(),
+		Directors:      p.GetDirectors(),
+		Cast:           cast,
+		RuntimeMinutes: p.GetRuntimeMinutes(),
+		Rating:         p.GetRating(),
+		Languages:      p.GetLanguages(),
+		Country:        p.GetCountry(),
+		Synopsis:       p.GetSynopsis(),
+		PosterURL:      p.GetPosterUrl(),
+		Version:        p.GetVersion(),
+	}
+}
+
+// ToProto converts g to its gRPC form, with its display name in lang.
+func (g Genre) ToProto(lang string) *pb.Genre {
+	return &pb.Genre{
+		Id:          g.ID,
+		Parent:      g.Parent,
+		Aliases:     g.Aliases,
+		Names:       g.Names,
+		DisplayName: g.DisplayName(lang),
+	}
+}
+
+// GenreFromProto converts a gRPC genre. The display name is not kept.
+func GenreFromProto(p *pb.Genre) Genre {
+	return Genre{
+		ID:      p.GetId(),
+		Parent:  p.GetParent(),
+		Aliases: p.GetAliases(),
+		Names:   p.GetNames(),
+	}
+}
@@ -0,0 +1,192 @@
+package catalog
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReleaseDateLayout is the DD-MM-YYYY format used for release dates.
+const ReleaseDateLayout = "02-01-2006"
+
+// Problem is a validation finding at a position in a catalog. Col is 0 when
+// only the line is known.
+type Problem struct {
+	Line, Col int
+	Msg       string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%d:%d: %s", p.Line, p.Col, p.Msg)
+}
+
+// element describes what an element of the catalog may contain.
+type element struct {
+	attrs    []string
+	children []string
+	text     bool
+}
+
+// schema lists the elements of a catalog by name; names are unique across
+// the document, so the parent alone decides what is allowed.
+var schema = map[string]element{
+	"movies":    {children: []string{"movie"}},
+	"movie":     {attrs: []string{"title", "genre", "releaseDate", "runtimeMinutes", "rating", "country"}, children: []string{"genres", "directors", "cast", "languages", "synopsis"}},
+	"genres":    {children: []string{"genre"}},
+	"genre":     {text: true},
+	"directors": {children: []string{"director"}},
+	"director":  {text: true},
+	"cast":      {children: []string{"member"}},
+	"member":    {attrs: []string{"name", "role"}},
+	"languages": {children: []string{"language"}},
+	"language":  {text: true},
+	"synopsis":  {text: true},
+}
+
+// RequiredAttrs must be present and non-empty on every <movie>.
+var RequiredAttrs = []string{"title", "genre", "releaseDate"}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate reads a catalog from r and returns every problem found, in
+// document order. It always reports a wrong root element, elements other
+// than <movie> below <movies>, missing required attributes, and malformed
+// release dates and runtimes. In strict mode it also reports unknown
+// elements and attributes anywhere, stray text, repeated attributes and
+// duplicate movies, i.e. the same title and release date.
+//
+// A malformed document is reported as a problem at the point where parsing
+// stopped. The error is only set when r itself fails.
+func Validate(r io.Reader, strict bool) ([]Problem, error) {
+	v := validator{strict: strict, seen: map[string]int{}}
+	d := xml.NewDecoder(r)
+
+	var stack []string
+	for {
+		line, col := d.InputPos()
+		tok, err := d.Token()
+		if err == io.EOF {
+			if len(stack) != 0 {
+				v.report(line, col, "unexpected end of file")
+			}
+			return v.problems, nil
+		}
+		var syntaxErr *xml.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			v.report(syntaxErr.Line, 0, "%s", syntaxErr.Msg)
+			return v.problems, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			if len(stack) == 0 {
+				if name != "movies" {
+					v.report(line, col, "root element is <%s>, want <movies>", name)
+					return v.problems, nil
+				}
+			} else if parent := stack[len(stack)-1]; !contains(schema[parent].children, name) {
+				if strict || parent == "movies" {
+					v.report(line, col, "unexpected element <%s> in <%s>", name, parent)
+				}
+				if err := d.Skip(); err != nil {
+					if errors.As(err, &syntaxErr) {
+						v.report(syntaxErr.Line, 0, "%s", syntaxErr.Msg)
+						return v.problems, nil
+					}
+					return nil, err
+				}
+				continue
+			}
+			v.checkAttrs(line, col, t)
+			stack = append(stack, name)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if strict && len(stack) > 0 && !schema[stack[len(stack)-1]].text && strings.TrimSpace(string(t)) != "" {
+				v.report(line, col, "unexpected text in <%s>", stack[len(stack)-1])
+			}
+		}
+	}
+}
+
+type validator struct {
+	strict   bool
+	seen     map[string]int // line of the first movie per duplicate key
+	problems []Problem
+}
+
+func (v *validator) report(line, col int, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{line, col, fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) checkAttrs(line, col int, t xml.StartElement) {
+	name := t.Name.Local
+	values := map[string]string{}
+	for _, a := range t.Attr {
+		if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
+			continue
+		}
+		if v.strict {
+			if !contains(schema[name].attrs, a.Name.Local) {
+				v.report(line, col, "unknown attribute %s on <%s>", a.Name.Local, name)
+			} else if _, ok := values[a.Name.Local]; ok {
+				v.report(line, col, "repeated attribute %s on <%s>", a.Name.Local, name)
+			}
+		}
+		values[a.Name.Local] = a.Value
+	}
+
+	switch name {
+	case "movie":
+		v.checkMovie(line, col, values)
+	case "member":
+		if strings.TrimSpace(values["name"]) == "" {
+			v.report(line, col, "cast member without a name")
+		}
+	}
+}
+
+func (v *validator) checkMovie(line, col int, values map[string]string) {
+	title := values["title"]
+	for _, name := range RequiredAttrs {
+		if strings.TrimSpace(values[name]) == "" {
+			v.report(line, col, "movie %q: missing %s", title, name)
+		}
+	}
+	date := strings.TrimSpace(values["releaseDate"])
+	if date != "" {
+		if _, err := time.Parse(ReleaseDateLayout, date); err != nil {
+			v.report(line, col, "movie %q: releaseDate %q is not a DD-MM-YYYY date", title, date)
+		}
+	}
+	if r, ok := values["runtimeMinutes"]; ok {
+		if n, err := strconv.Atoi(strings.TrimSpace(r)); err != nil || n <= 0 {
+			v.report(line, col, "movie %q: runtimeMinutes %q is not a positive number", title, r)
+		}
+	}
+
+	if !v.strict || strings.TrimSpace(title) == "" {
+		return
+	}
+	key := strings.ToLower(strings.TrimSpace(title)) + "\x00" + date
+	if first, ok := v.seen[key]; ok {
+		v.report(line, col, "movie %q: duplicate of the movie on line %d", title, first)
+		return
+	}
+	v.seen[key] = line
+}
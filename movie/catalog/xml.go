@@ -0,0 +1,70 @@
+package catalog
+
+import (
+	"encoding/xml"
+	"strconv"
+)
+
+// MarshalXML writes m as a <movie> element. Unlike the default encoding it
+// leaves out empty attributes and list wrappers such as <genres></genres>.
+func (m Movie) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "movie"}}
+	runtime := ""
+	if m.RuntimeMinutes != 0 {
+		runtime = strconv.Itoa(int(m.RuntimeMinutes))
+	}
+	for _, a := range [][2]string{
+		{"title", m.Title},
+		{"genre", m.Genre},
+		{"releaseDate", m.ReleaseDate},
+		{"runtimeMinutes", runtime},
+		{"rating", m.Rating},
+		{"country", m.Country},
+	} {
+		if a[1] != "" {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: a[0]}, Value: a[1]})
+		}
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if err := encodeList(enc, "genres", "genre", m.Genres); err != nil {
+		return err
+	}
+	if err := encodeList(enc, "directors", "director", m.Directors); err != nil {
+		return err
+	}
+	if len(m.Cast) > 0 {
+		if err := enc.EncodeElement(struct {
+			Members []CastMember `xml:"member"`
+		}{m.Cast}, xml.StartElement{Name: xml.Name{Local: "cast"}}); err != nil {
+			return err
+		}
+	}
+	if err := encodeList(enc, "languages", "language", m.Languages); err != nil {
+		return err
+	}
+	if m.Synopsis != "" {
+		if err := enc.EncodeElement(m.Synopsis, xml.StartElement{Name: xml.Name{Local: "synopsis"}}); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func encodeList(enc *xml.Encoder, wrapper, item string, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	start := xml.StartElement{Name: xml.Name{Local: wrapper}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, v := range values {
+		if err := enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: item}}); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"movie/telemetry"
+)
+
+// Config holds the settings of the HTTP gateway.
+type Config struct {
+	Addr          string `yaml:"addr" env:"MOVIE_GATEWAY_ADDR" flag:"addr" usage:"HTTP listen address"`
+	Backend       string `yaml:"backend" env:"MOVIE_BACKEND_ADDR" flag:"backend" usage:"address of the gRPC server"`
+	StrictXML     bool   `yaml:"strict_xml" env:"MOVIE_GATEWAY_STRICT_XML" flag:"strict-xml" usage:"reject catalogs with unknown elements, attributes or duplicate movies"`
+	TraceExporter string `yaml:"trace_exporter" env:"OTEL_TRACES_EXPORTER" flag:"trace-exporter" usage:"span exporter: otlp, stdout or none"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Addr:          ":8080",
+		Backend:       "localhost:50051",
+		TraceExporter: telemetry.ExporterOTLP,
+	}
+}
+
+// Validate reports the first invalid setting.
+func (c *Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("addr: %w", err)
+	}
+	if _, _, err := net.SplitHostPort(c.Backend); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+	switch c.TraceExporter {
+	case telemetry.ExporterOTLP, telemetry.ExporterStdout, telemetry.ExporterNone:
+	default:
+		return fmt.Errorf("trace_exporter: unknown exporter %q", c.TraceExporter)
+	}
+	return nil
+}
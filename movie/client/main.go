@@ -1,52 +1,138 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"movie/catalog"
+	"movie/config"
 	pb "movie/proto"
+	"movie/telemetry"
 	"net/http"
+	"os"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-// Movie represents a movie with title, genre, and release date.
-type Movie struct {
-	Title       string `xml:"title,attr"`
-	Genre       string `xml:"genre,attr"`
-	ReleaseDate string `xml:"releaseDate,attr"`
-}
+var tracer = otel.Tracer("movie/client")
+
+// backendAddr is the address of the gRPC server, set from Config.Backend.
+var backendAddr string
+
+// dialTimeout bounds how long dialBackend waits for the gRPC server.
+const dialTimeout = 5 * time.Second
 
-// Movies represents a collection of movies.
-type Movies struct {
-	XMLName xml.Name `xml:"movies"`
-	Movies  []Movie  `xml:"movie"`
+// dialBackend connects to the gRPC server. It blocks until the connection is
+// up, so the grpc.dial span covers the actual connection setup. Trace
+// context is injected into the outgoing metadata of every call made on the
+// returned connection.
+func dialBackend(ctx context.Context) (*grpc.ClientConn, error) {
+	ctx, span := tracer.Start(ctx, "grpc.dial")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, backendAddr,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+		return nil, fmt.Errorf("connect to %s: %w", backendAddr, err)
+	}
+	return conn, nil
 }
 
-// MovieLibrary represents a simple movie library.
-var MovieLibrary Movies
+// strictXML enables the strict checks of catalog.Validate, set from
+// Config.StrictXML.
+var strictXML bool
+
+// maxCatalogBytes bounds the size of an uploaded XML catalog.
+const maxCatalogBytes = 32 << 20
+
+// decodeCatalog validates the XML catalog in the request body and returns
+// its movies. When the document is invalid it replies with 400 and
+// every problem found, one per line, and returns false; a body larger than
+// maxCatalogBytes gets 413.
+func decodeCatalog(w http.ResponseWriter, r *http.Request) (catalog.Movies, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCatalogBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("Catalog larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return catalog.Movies{}, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return catalog.Movies{}, false
+	}
 
-func resetMovieLibrary() {
-	MovieLibrary = Movies{}
+	problems, err := catalog.Validate(bytes.NewReader(body), strictXML)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return catalog.Movies{}, false
+	}
+	if len(problems) > 0 {
+		var msg strings.Builder
+		fmt.Fprintf(&msg, "Invalid XML: %d problem(s)\n", len(problems))
+		for _, p := range problems {
+			fmt.Fprintln(&msg, p)
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, msg.String())
+		return catalog.Movies{}, false
+	}
+
+	var library catalog.Movies
+	if err := xml.Unmarshal(body, &library); err != nil {
+		http.Error(w, "Failed to decode XML", http.StatusBadRequest)
+		return catalog.Movies{}, false
+	}
+	return library, true
 }
 
 func loadMovieLibrary(w http.ResponseWriter, r *http.Request) {
-	resetMovieLibrary()
+	var dryRun bool
+	if s := r.URL.Query().Get("dryRun"); s != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(s); err != nil {
+			http.Error(w, "Invalid dryRun", http.StatusBadRequest)
+			return
+		}
+	}
 
-	decoder := xml.NewDecoder(r.Body)
-	if err := decoder.Decode(&MovieLibrary); err != nil {
-		http.Error(w, "Failed to decode XML", http.StatusBadRequest)
+	library, ok := decodeCatalog(w, r)
+	if !ok {
 		return
 	}
 
 	//////////////////////////////////
-	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	conn, err := dialBackend(r.Context())
 	if err != nil {
-		log.Fatalf("Failed to connect to gRPC server: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
 	}
 	defer conn.Close()
 
@@ -54,31 +140,40 @@ func loadMovieLibrary(w http.ResponseWriter, r *http.Request) {
 	client := pb.NewMovieLibraryServiceClient(conn)
 
 	// Send the movie records to the gRPC service.
-	movies := make([]*pb.Movie, len(MovieLibrary.Movies))
+	movies := make([]*pb.Movie, len(library.Movies))
 
-	for i, v := range MovieLibrary.Movies {
-		movies[i] = &pb.Movie{
-			Title:       v.Title,
-			Genre:       v.Genre,
-			ReleaseDate: v.ReleaseDate,
-		}
+	for i, v := range library.Movies {
+		movies[i] = v.ToProto()
 	}
 
-	//fmt.Println(MovieLibrary.Movies)
+	//fmt.Println(library.Movies)
 
 	request := &pb.MovieRequest{
 		Movies: movies,
+		DryRun: dryRun,
 	}
 	fmt.Println(request)
 
 	// Set a context with a timeout.
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
 	// Call the gRPC service's LoadMovies method.
 	response, err := client.LoadMovies(ctx, request)
 	if err != nil {
-		log.Fatalf("Failed to send movie records to gRPC service: %v", err)
+		writeRPCError(w, err)
+		return
+	}
+
+	if request.DryRun {
+		data, err := json.Marshal(response.Preview)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+		return
 	}
 
 	// Check the response status code.
@@ -100,9 +195,10 @@ func getMovieLibrary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	conn, err := dialBackend(r.Context())
 	if err != nil {
-		log.Fatalf("Failed to connect to gRPC server: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
 	}
 	defer conn.Close()
 
@@ -116,9 +212,10 @@ func getMovieLibrary(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call the gRPC service's GetMovieDetails method.
-	resp, err := client.GetMovieDetails(context.Background(), request)
+	resp, err := client.GetMovieDetails(r.Context(), request)
 	if err != nil {
-		log.Fatalf("Failed to query movie details: %v", err)
+		writeRPCError(w, err)
+		return
 	}
 
 	fmt.Println(resp)
@@ -140,15 +237,21 @@ func updateMovieLibrary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	decoder := xml.NewDecoder(r.Body)
-	if err := decoder.Decode(&MovieLibrary); err != nil {
-		http.Error(w, "Failed to decode XML", http.StatusBadRequest)
+	expectedVersions, ok := parseIfMatch(r.Header.Get("If-Match"))
+	if !ok {
+		http.Error(w, "If-Match must be * or ETags of the movie", http.StatusPreconditionFailed)
 		return
 	}
 
-	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	library, ok := decodeCatalog(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := dialBackend(r.Context())
 	if err != nil {
-		log.Fatalf("Failed to connect to gRPC server: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
 	}
 	defer conn.Close()
 
@@ -159,24 +262,22 @@ func updateMovieLibrary(w http.ResponseWriter, r *http.Request) {
 	movieID := int32(parsedID)
 	updatedMovie := &pb.Movie{}
 
-	for _, v := range MovieLibrary.Movies {
-		updatedMovie = &pb.Movie{
-			Title:       v.Title,
-			Genre:       v.Genre,
-			ReleaseDate: v.ReleaseDate,
-		}
+	for _, v := range library.Movies {
+		updatedMovie = v.ToProto()
 	}
 
 	// Create a request for updating movie details.
 	request := &pb.UpdateMovieDetailsRequest{
-		MovieId:      movieID,
-		UpdatedMovie: updatedMovie,
+		MovieId:          movieID,
+		UpdatedMovie:     updatedMovie,
+		ExpectedVersions: expectedVersions,
 	}
 
 	// Call the gRPC service's UpdateMovieDetails method.
-	response, err := client.UpdateMovieDetails(context.Background(), request)
+	response, err := client.UpdateMovieDetails(r.Context(), request)
 	if err != nil {
-		log.Fatalf("Failed to update movie details: %v", err)
+		writeRPCError(w, err)
+		return
 	}
 
 	if response.StatusCode == 201 {
@@ -184,10 +285,12 @@ func updateMovieLibrary(w http.ResponseWriter, r *http.Request) {
 		fmt.Println("Updated Movie Details:")
 		updatedMovieJSON, err := json.Marshal(response.UpdatedMovie)
 		if err != nil {
-			log.Fatalf("Failed to marshal updated movie details to JSON: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", versionETag(response.UpdatedMovie.GetVersion()))
 		w.WriteHeader(http.StatusCreated)
 		w.Write(updatedMovieJSON)
 		return
@@ -197,6 +300,53 @@ func updateMovieLibrary(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// versionETag returns the ETag of a movie at version v.
+func versionETag(v int64) string {
+	return `"` + strconv.FormatInt(v, 10) + `"`
+}
+
+// parseIfMatch returns the movie versions an If-Match header lists, none
+// when the header is empty or "*". Weak tags and tags that are not movie
+// versions never match an update; a header with nothing else, or one that
+// is malformed, is rejected.
+func parseIfMatch(h string) ([]int64, bool) {
+	h = strings.TrimSpace(h)
+	if h == "" || h == "*" {
+		return nil, true
+	}
+	var versions []int64
+	for {
+		h = strings.TrimLeft(h, " \t,")
+		if h == "" {
+			break
+		}
+		weak := strings.HasPrefix(h, "W/")
+		if weak {
+			h = h[len("W/"):]
+		}
+		// Tags are quoted and may themselves contain commas.
+		if h == "" || h[0] != '"' {
+			return nil, false
+		}
+		end := strings.IndexByte(h[1:], '"') + 1
+		if end == 0 {
+			return nil, false
+		}
+		tag := h[1:end]
+		h = strings.TrimLeft(h[end+1:], " \t")
+		if h != "" && h[0] != ',' {
+			return nil, false
+		}
+		if v, err := strconv.ParseInt(tag, 10, 64); err == nil && v > 0 && !weak {
+			versions = append(versions, v)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, false
+	}
+	return versions, true
+}
+
 func getUpdateMovieLibrary(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPost {
 		updateMovieLibrary(w, r)
@@ -210,16 +360,486 @@ func getUpdateMovieLibrary(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// writeRPCError replies with the HTTP status matching the gRPC error.
+func writeRPCError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	code := http.StatusBadGateway
+	switch st.Code() {
+	case codes.InvalidArgument:
+		code = http.StatusBadRequest
+	case codes.NotFound:
+		code = http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		code = http.StatusConflict
+	case codes.FailedPrecondition:
+		code = http.StatusPreconditionFailed
+	case codes.ResourceExhausted:
+		code = http.StatusRequestEntityTooLarge
+	case codes.OutOfRange:
+		code = http.StatusGone
+	case codes.Unimplemented:
+		code = http.StatusNotImplemented
+	case codes.DeadlineExceeded:
+		code = http.StatusGatewayTimeout
+	}
+	http.Error(w, st.Message(), code)
+}
+
+// moviesHandler serves the per-movie resources below /movie-library/movies/.
+func moviesHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/movie-library/movies/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid movie id", http.StatusBadRequest)
+		return
+	}
+
+	switch parts[1] {
+	case "poster":
+		getPoster(w, r, int32(id))
+	case "history":
+		getMovieHistory(w, r, int32(id))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// getMovieHistory serves the audit log entries of a movie as JSON, the
+// latest ?limit= of them when given.
+func getMovieHistory(w http.ResponseWriter, r *http.Request, movieID int32) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	var limit int64
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.ParseInt(s, 10, 32); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	conn, err := dialBackend(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+
+	client := pb.NewMovieLibraryServiceClient(conn)
+	resp, err := client.GetMovieHistory(r.Context(), &pb.GetMovieHistoryRequest{MovieId: movieID, Limit: int32(limit)})
+	if err != nil {
+		writeRPCError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// snapshotsHandler lists the library snapshots on GET /movie-library/snapshots
+// and restores one on POST /movie-library/snapshots/{id}/restore.
+func snapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/movie-library/snapshots"), "/")
+	if rest == "" {
+		listSnapshots(w, r)
+		return
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 || parts[1] != "restore" {
+		http.NotFound(w, r)
+		return
+	}
+	restoreSnapshot(w, r, parts[0])
+}
+
+func listSnapshots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	conn, err := dialBackend(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+
+	client := pb.NewMovieLibraryServiceClient(conn)
+	resp, err := client.ListSnapshots(r.Context(), &pb.ListSnapshotsRequest{})
+	if err != nil {
+		writeRPCError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func restoreSnapshot(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	conn, err := dialBackend(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+
+	client := pb.NewMovieLibraryServiceClient(conn)
+	resp, err := client.RestoreSnapshot(r.Context(), &pb.RestoreSnapshotRequest{Id: id})
+	if err != nil {
+		writeRPCError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// streamEvents relays WatchMovies as Server-Sent Events. Every event has its
+// sequence number as id, so browsers resume with Last-Event-ID when they
+// reconnect; other clients can pass ?after= instead.
+func streamEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	var after uint64
+	last := r.Header.Get("Last-Event-ID")
+	if s := r.URL.Query().Get("after"); s != "" {
+		last = s
+	}
+	if last != "" {
+		var err error
+		if after, err = strconv.ParseUint(last, 10, 64); err != nil {
+			http.Error(w, "Invalid event id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	conn, err := dialBackend(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+
+	client := pb.NewMovieLibraryServiceClient(conn)
+	stream, err := client.WatchMovies(r.Context(), &pb.WatchMoviesRequest{AfterSeq: after})
+	if err == nil {
+		// The server sends headers once it has accepted the subscription;
+		// without them the stream failed, and Recv returns why.
+		var md metadata.MD
+		if md, err = stream.Header(); err == nil && md == nil {
+			_, err = stream.Recv()
+		}
+	}
+	if err != nil {
+		writeRPCError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		ev, err := stream.Recv()
+		if err != nil {
+			if r.Context().Err() == nil {
+				data, _ := json.Marshal(map[string]string{"error": status.Convert(err).Message()})
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+			}
+			return
+		}
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, data)
+		flusher.Flush()
+	}
+}
+
+// getPoster serves a movie poster. The whole image is fetched from the gRPC
+// server; http.ServeContent then handles HEAD, Range and conditional
+// requests against its ETag and modification time.
+func getPoster(w http.ResponseWriter, r *http.Request, movieID int32) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	conn, err := dialBackend(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+
+	client := pb.NewMovieLibraryServiceClient(conn)
+	stream, err := client.GetPoster(r.Context(), &pb.GetPosterRequest{MovieId: movieID})
+	if err != nil {
+		writeRPCError(w, err)
+		return
+	}
+
+	var info *pb.PosterInfo
+	var image bytes.Buffer
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeRPCError(w, err)
+			return
+		}
+		if i := resp.GetInfo(); i != nil {
+			info = i
+			image.Grow(int(i.Size))
+		}
+		image.Write(resp.GetChunk())
+	}
+	if info == nil {
+		http.Error(w, "Poster info missing from response", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("ETag", `"`+info.Etag+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	http.ServeContent(w, r, "", time.Unix(info.ModifiedUnix, 0), bytes.NewReader(image.Bytes()))
+}
+
+// getGenres serves the genre taxonomy as JSON, with display names in the
+// language given by ?lang=.
+func getGenres(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	conn, err := dialBackend(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+
+	client := pb.NewMovieLibraryServiceClient(conn)
+	resp, err := client.ListGenres(r.Context(), &pb.ListGenresRequest{Language: r.URL.Query().Get("lang")})
+	if err != nil {
+		writeRPCError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// suggestion is a "did you mean" entry of a failed title lookup.
+type suggestion struct {
+	ID    int32   `json:"id"`
+	Title string  `json:"title"`
+	Score float64 `json:"score"`
+}
+
+// maxSuggestions is the number of titles offered when a lookup fails.
+const maxSuggestions = 5
+
+// findMoviesByTitle serves the movies titled ?title=, ignoring case. When
+// there are none it replies 404 with the closest titles as suggestions.
+func findMoviesByTitle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	title := strings.TrimSpace(r.URL.Query().Get("title"))
+	if title == "" {
+		http.Error(w, "Missing query parameter title", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := dialBackend(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+
+	client := pb.NewMovieLibraryServiceClient(conn)
+	resp, err := client.GetMovieDetails(r.Context(), &pb.GetMovieDetailsRequest{
+		TitlePattern: `(?i)^\s*` + regexp.QuoteMeta(title) + `\s*$`,
+	})
+	if err != nil {
+		writeRPCError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(resp.Movies) > 0 {
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	fuzzy, err := client.FuzzyTitleLookup(r.Context(), &pb.FuzzyTitleLookupRequest{Title: title, Limit: maxSuggestions})
+	if err != nil && status.Code(err) != codes.Unimplemented {
+		w.Header().Del("Content-Type")
+		writeRPCError(w, err)
+		return
+	}
+	body := struct {
+		Error      string       `json:"error"`
+		DidYouMean []suggestion `json:"didYouMean"`
+	}{Error: fmt.Sprintf("no movie titled %q", title), DidYouMean: []suggestion{}}
+	for _, m := range fuzzy.GetMatches() {
+		body.DidYouMean = append(body.DidYouMean, suggestion{m.Movie.GetId(), m.Movie.GetTitle(), m.Score})
+	}
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(body)
+}
+
+// searchMovies serves full-text search results as JSON for ?q=, with an
+// optional ?limit=.
+func searchMovies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	if q.Get("q") == "" {
+		http.Error(w, "Missing query parameter q", http.StatusBadRequest)
+		return
+	}
+	var limit int64
+	if s := q.Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.ParseInt(s, 10, 32); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	conn, err := dialBackend(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+
+	client := pb.NewMovieLibraryServiceClient(conn)
+	resp, err := client.FullTextSearch(r.Context(), &pb.FullTextSearchRequest{Query: q.Get("q"), Limit: int32(limit)})
+	if err != nil {
+		writeRPCError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
 func apiHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "gRPC api ready!!!")
 }
 
+// handle registers fn on pattern with a server span named after the route.
+func handle(pattern string, fn http.HandlerFunc) {
+	http.Handle(pattern, otelhttp.NewHandler(withRequestInfo(fn), pattern))
+}
+
+// withRequestInfo passes the X-Actor and X-Request-Id headers on to the gRPC
+// server as metadata, for its audit log, and Idempotency-Key so retried
+// writes are applied only once. Requests without an ID get a random one,
+// which is echoed in the response.
+func withRequestInfo(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if id == "" {
+			b := make([]byte, 8)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set("X-Request-Id", id)
+
+		md := []string{"x-request-id", id}
+		if actor := r.Header.Get("X-Actor"); actor != "" {
+			md = append(md, "x-actor", actor)
+		}
+		if key := r.Header.Get("Idempotency-Key"); key != "" {
+			md = append(md, "idempotency-key", key)
+		}
+		fn(w, r.WithContext(metadata.AppendToOutgoingContext(r.Context(), md...)))
+	}
+}
+
 func main() {
+	cfg := defaultConfig()
+	if err := config.Load("gateway", "MOVIE_GATEWAY_CONFIG", &cfg, os.Args[1:]); err != nil {
+		if errors.Is(err, config.ErrPrinted) || errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	backendAddr = cfg.Backend
+	strictXML = cfg.StrictXML
 
-	http.HandleFunc("/", apiHandler)
-	http.HandleFunc("/movie-library/load", loadMovieLibrary)
-	http.HandleFunc("/movie-library/movie/", getUpdateMovieLibrary)
-	port := ":8080"
-	fmt.Printf("gRPC client is listening on port %s...\n", port)
-	http.ListenAndServe(port, nil)
+	shutdown, err := telemetry.Setup(context.Background(), "movie-library-gateway", cfg.TraceExporter)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	handle("/", apiHandler)
+	handle("/movie-library/load", loadMovieLibrary)
+	handle("/movie-library/movie/", getUpdateMovieLibrary)
+	handle("/movie-library/movies", findMoviesByTitle)
+	handle("/movie-library/movies/", moviesHandler)
+	handle("/movie-library/genres", getGenres)
+	handle("/movie-library/search", searchMovies)
+	handle("/movie-library/snapshots", snapshotsHandler)
+	handle("/movie-library/snapshots/", snapshotsHandler)
+	handle("/movie-library/events", streamEvents)
+	fmt.Printf("gRPC client is listening on port %s...\n", cfg.Addr)
+	http.ListenAndServe(cfg.Addr, nil)
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		h      string
+		want   []int64
+		wantOK bool
+	}{
+		{"", nil, true},
+		{" * ", nil, true},
+		{`"3"`, []int64{3}, true},
+		{`"3", "4"`, []int64{3, 4}, true},
+		{`"3","4",`, []int64{3, 4}, true},
+		{`W/"3", "4"`, []int64{4}, true},
+		{`"a,b", "5"`, []int64{5}, true},
+		{`W/"3"`, nil, false},
+		{`"abc"`, nil, false},
+		{`"0"`, nil, false},
+		{`3`, nil, false},
+		{`"3`, nil, false},
+		{`"3" "4"`, nil, false},
+		{`"3", *`, nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseIfMatch(tt.h)
+		if !reflect.DeepEqual(got, tt.want) || ok != tt.wantOK {
+			t.Errorf("parseIfMatch(%q) = %v, %v, want %v, %v", tt.h, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestWriteRPCError(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.FailedPrecondition, http.StatusPreconditionFailed},
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.OutOfRange, http.StatusGone},
+		{codes.Unavailable, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		writeRPCError(w, status.Error(tt.code, "movie 1 is at version 2, not 1"))
+		if w.Code != tt.want {
+			t.Errorf("%v: status %d, want %d", tt.code, w.Code, tt.want)
+		}
+	}
+}
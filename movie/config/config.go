@@ -0,0 +1,220 @@
+// Package config resolves the typed configuration of the movie library
+// binaries. Settings are read, in increasing order of precedence, from the
+// defaults already held by the struct, an optional YAML file, environment
+// variables and command-line flags.
+//
+// Each exported field of the config struct is described by struct tags:
+//
+//	Addr string `yaml:"addr" env:"MOVIE_SERVER_ADDR" flag:"addr" usage:"listen address"`
+//
+// Supported field types are string, bool, int, float64 and time.Duration.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrPrinted is returned by Load when -print-config was given and the
+// resolved configuration has been written out instead of being used.
+var ErrPrinted = errors.New("config: printed")
+
+// Validator is implemented by config structs that check their own values
+// once every source has been applied.
+type Validator interface {
+	Validate() error
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load fills cfg, a pointer to a struct, from the config file, the
+// environment and args. The file is named by the -config flag, falling back
+// to the environment variable fileEnv. name is used in usage messages.
+func Load(name, fileEnv string, cfg interface{}, args []string) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: %T is not a pointer to a struct", cfg)
+	}
+	v = v.Elem()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := fs.String("config", os.Getenv(fileEnv), "optional YAML config file (env "+fileEnv+")")
+	printOnly := fs.Bool("print-config", false, "print the resolved configuration and exit")
+
+	// Flags are parsed into a scratch copy so that explicitly set values can
+	// be laid over the file and environment afterwards.
+	flagged := reflect.New(v.Type()).Elem()
+	flagged.Set(v)
+	if err := forEachField(flagged, func(f fieldInfo) error {
+		if f.flag == "" {
+			return nil
+		}
+		return defineFlag(fs, f)
+	}); err != nil {
+		return err
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *file != "" {
+		if err := readFile(*file, cfg); err != nil {
+			return err
+		}
+	}
+
+	if err := forEachField(v, func(f fieldInfo) error {
+		if f.env == "" {
+			return nil
+		}
+		s, ok := os.LookupEnv(f.env)
+		if !ok {
+			return nil
+		}
+		if err := setString(f.value, s); err != nil {
+			return fmt.Errorf("config: %s: %w", f.env, err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for i := 0; i < v.NumField(); i++ {
+		if name := v.Type().Field(i).Tag.Get("flag"); name != "" && set[name] {
+			v.Field(i).Set(flagged.Field(i))
+		}
+	}
+
+	if val, ok := cfg.(Validator); ok {
+		if err := val.Validate(); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+
+	if *printOnly {
+		if err := Print(os.Stdout, cfg); err != nil {
+			return err
+		}
+		return ErrPrinted
+	}
+	return nil
+}
+
+// Print writes cfg to w as YAML.
+func Print(w io.Writer, cfg interface{}) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func readFile(path string, cfg interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+type fieldInfo struct {
+	value reflect.Value
+	flag  string
+	env   string
+	usage string
+}
+
+func forEachField(v reflect.Value, fn func(fieldInfo) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		if err := fn(fieldInfo{
+			value: v.Field(i),
+			flag:  sf.Tag.Get("flag"),
+			env:   sf.Tag.Get("env"),
+			usage: sf.Tag.Get("usage"),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func defineFlag(fs *flag.FlagSet, f fieldInfo) error {
+	usage := f.usage
+	if f.env != "" {
+		usage += " (env " + f.env + ")"
+	}
+	switch p := f.value.Addr().Interface().(type) {
+	case *string:
+		fs.StringVar(p, f.flag, *p, usage)
+	case *bool:
+		fs.BoolVar(p, f.flag, *p, usage)
+	case *int:
+		fs.IntVar(p, f.flag, *p, usage)
+	case *float64:
+		fs.Float64Var(p, f.flag, *p, usage)
+	case *time.Duration:
+		fs.DurationVar(p, f.flag, *p, usage)
+	default:
+		return fmt.Errorf("config: unsupported type %s for flag -%s", f.value.Type(), f.flag)
+	}
+	return nil
+}
+
+func setString(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(x)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
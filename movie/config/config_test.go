@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Name    string        `yaml:"name" env:"CONFIG_TEST_NAME" flag:"name"`
+	Verbose bool          `yaml:"verbose" env:"CONFIG_TEST_VERBOSE" flag:"verbose"`
+	Retries int           `yaml:"retries" env:"CONFIG_TEST_RETRIES" flag:"retries"`
+	Ratio   float64       `yaml:"ratio" env:"CONFIG_TEST_RATIO" flag:"ratio"`
+	Timeout time.Duration `yaml:"timeout" env:"CONFIG_TEST_TIMEOUT" flag:"timeout"`
+}
+
+func (c *testConfig) Validate() error {
+	if c.Retries < 0 {
+		return errors.New("retries must not be negative")
+	}
+	return nil
+}
+
+func defaultTestConfig() testConfig {
+	return testConfig{Name: "default", Retries: 3, Ratio: 0.5, Timeout: time.Second}
+}
+
+// writeConfig writes a YAML config file and returns its path.
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want testConfig
+	}{
+		{
+			name: "defaults",
+			want: defaultTestConfig(),
+		},
+		{
+			name: "file over defaults",
+			file: "name: file\nretries: 5\ntimeout: 2m\n",
+			want: testConfig{Name: "file", Retries: 5, Ratio: 0.5, Timeout: 2 * time.Minute},
+		},
+		{
+			name: "env over file",
+			file: "name: file\nretries: 5\n",
+			env:  map[string]string{"CONFIG_TEST_NAME": "env", "CONFIG_TEST_VERBOSE": "true", "CONFIG_TEST_RATIO": "0.25"},
+			want: testConfig{Name: "env", Verbose: true, Retries: 5, Ratio: 0.25, Timeout: time.Second},
+		},
+		{
+			name: "flags over env and file",
+			file: "name: file\nretries: 5\n",
+			env:  map[string]string{"CONFIG_TEST_NAME": "env", "CONFIG_TEST_TIMEOUT": "1h"},
+			args: []string{"-name", "flag", "-timeout", "90s"},
+			want: testConfig{Name: "flag", Retries: 5, Ratio: 0.5, Timeout: 90 * time.Second},
+		},
+		{
+			name: "flag set to the default still wins",
+			env:  map[string]string{"CONFIG_TEST_RETRIES": "9"},
+			args: []string{"-retries=3"},
+			want: defaultTestConfig(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfig(t, tt.file)}, args...)
+			}
+			cfg := defaultTestConfig()
+			if err := Load("test", "CONFIG_TEST_FILE", &cfg, args); err != nil {
+				t.Fatal(err)
+			}
+			if cfg != tt.want {
+				t.Errorf("got %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileFromEnv(t *testing.T) {
+	t.Setenv("CONFIG_TEST_FILE", writeConfig(t, "name: file\n"))
+	cfg := defaultTestConfig()
+	if err := Load("test", "CONFIG_TEST_FILE", &cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "file" {
+		t.Errorf("name %q, want %q", cfg.Name, "file")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{name: "unknown yaml key", file: "name: file\ncolour: blue\n", want: "field colour not found"},
+		{name: "bad yaml value", file: "retries: many\n", want: "cannot unmarshal"},
+		{name: "bad env value", env: map[string]string{"CONFIG_TEST_RETRIES": "many"}, want: "CONFIG_TEST_RETRIES"},
+		{name: "unknown flag", args: []string{"-colour", "blue"}, want: "flag provided but not defined"},
+		{name: "invalid", args: []string{"-retries", "-1"}, want: "retries must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfig(t, tt.file)}, args...)
+			}
+			cfg := defaultTestConfig()
+			err := Load("test", "CONFIG_TEST_FILE", &cfg, args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
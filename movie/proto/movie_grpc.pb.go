@@ -25,6 +25,43 @@ type MovieLibraryServiceClient interface {
 	LoadMovies(ctx context.Context, in *MovieRequest, opts ...grpc.CallOption) (*MovieResponse, error)
 	GetMovieDetails(ctx context.Context, in *GetMovieDetailsRequest, opts ...grpc.CallOption) (*GetMovieDetailsResponse, error)
 	UpdateMovieDetails(ctx context.Context, in *UpdateMovieDetailsRequest, opts ...grpc.CallOption) (*UpdateMovieDetailsResponse, error)
+	// UploadPoster stores the image for a movie. The first message carries the
+	// poster info, the following ones the image bytes.
+	UploadPoster(ctx context.Context, opts ...grpc.CallOption) (MovieLibraryService_UploadPosterClient, error)
+	// GetPoster streams the poster info followed by the image bytes.
+	GetPoster(ctx context.Context, in *GetPosterRequest, opts ...grpc.CallOption) (MovieLibraryService_GetPosterClient, error)
+	// ListGenres returns the genre taxonomy.
+	ListGenres(ctx context.Context, in *ListGenresRequest, opts ...grpc.CallOption) (*ListGenresResponse, error)
+	// UpsertGenre adds a genre or replaces the one with the same id.
+	UpsertGenre(ctx context.Context, in *UpsertGenreRequest, opts ...grpc.CallOption) (*UpsertGenreResponse, error)
+	// FullTextSearch finds movies by words of their title or synopsis.
+	FullTextSearch(ctx context.Context, in *FullTextSearchRequest, opts ...grpc.CallOption) (*FullTextSearchResponse, error)
+	// FuzzyTitleLookup finds movies whose title is close to the given one,
+	// tolerating typos, case and punctuation.
+	FuzzyTitleLookup(ctx context.Context, in *FuzzyTitleLookupRequest, opts ...grpc.CallOption) (*FuzzyTitleLookupResponse, error)
+	// FindDuplicates groups movies that probably describe the same movie.
+	FindDuplicates(ctx context.Context, in *FindDuplicatesRequest, opts ...grpc.CallOption) (*FindDuplicatesResponse, error)
+	// MergeMovies folds duplicates into a surviving movie and removes them.
+	MergeMovies(ctx context.Context, in *MergeMoviesRequest, opts ...grpc.CallOption) (*MergeMoviesResponse, error)
+	// GetMovieHistory returns the audit log entries of a movie.
+	GetMovieHistory(ctx context.Context, in *GetMovieHistoryRequest, opts ...grpc.CallOption) (*GetMovieHistoryResponse, error)
+	// ListSnapshots lists the saved versions of the library, newest first.
+	ListSnapshots(ctx context.Context, in *ListSnapshotsRequest, opts ...grpc.CallOption) (*ListSnapshotsResponse, error)
+	// RestoreSnapshot replaces the library with a saved version. The library
+	// it replaces is snapshotted first, so a restore can be undone as well.
+	RestoreSnapshot(ctx context.Context, in *RestoreSnapshotRequest, opts ...grpc.CallOption) (*RestoreSnapshotResponse, error)
+	// WatchMovies streams every change to the library as it happens.
+	WatchMovies(ctx context.Context, in *WatchMoviesRequest, opts ...grpc.CallOption) (MovieLibraryService_WatchMoviesClient, error)
+	// RegisterWebhook subscribes a URL to library changes. Every matching
+	// MovieEvent is POSTed to it as JSON, signed with the secret.
+	RegisterWebhook(ctx context.Context, in *RegisterWebhookRequest, opts ...grpc.CallOption) (*RegisterWebhookResponse, error)
+	ListWebhooks(ctx context.Context, in *ListWebhooksRequest, opts ...grpc.CallOption) (*ListWebhooksResponse, error)
+	DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error)
+	// ListWebhookDeliveries returns the delivery log of a webhook.
+	ListWebhookDeliveries(ctx context.Context, in *ListWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ListWebhookDeliveriesResponse, error)
+	// BatchUpdateMovies applies many updates with a single write of the
+	// library.
+	BatchUpdateMovies(ctx context.Context, in *BatchUpdateMoviesRequest, opts ...grpc.CallOption) (*BatchUpdateMoviesResponse, error)
 }
 
 type movieLibraryServiceClient struct {
@@ -62,6 +99,230 @@ func (c *movieLibraryServiceClient) UpdateMovieDetails(ctx context.Context, in *
 	return out, nil
 }
 
+func (c *movieLibraryServiceClient) UploadPoster(ctx context.Context, opts ...grpc.CallOption) (MovieLibraryService_UploadPosterClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MovieLibraryService_ServiceDesc.Streams[0], "/movie_library.MovieLibraryService/UploadPoster", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &movieLibraryServiceUploadPosterClient{stream}
+	return x, nil
+}
+
+type MovieLibraryService_UploadPosterClient interface {
+	Send(*UploadPosterRequest) error
+	CloseAndRecv() (*UploadPosterResponse, error)
+	grpc.ClientStream
+}
+
+type movieLibraryServiceUploadPosterClient struct {
+	grpc.ClientStream
+}
+
+func (x *movieLibraryServiceUploadPosterClient) Send(m *UploadPosterRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *movieLibraryServiceUploadPosterClient) CloseAndRecv() (*UploadPosterResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadPosterResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *movieLibraryServiceClient) GetPoster(ctx context.Context, in *GetPosterRequest, opts ...grpc.CallOption) (MovieLibraryService_GetPosterClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MovieLibraryService_ServiceDesc.Streams[1], "/movie_library.MovieLibraryService/GetPoster", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &movieLibraryServiceGetPosterClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MovieLibraryService_GetPosterClient interface {
+	Recv() (*GetPosterResponse, error)
+	grpc.ClientStream
+}
+
+type movieLibraryServiceGetPosterClient struct {
+	grpc.ClientStream
+}
+
+func (x *movieLibraryServiceGetPosterClient) Recv() (*GetPosterResponse, error) {
+	m := new(GetPosterResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *movieLibraryServiceClient) ListGenres(ctx context.Context, in *ListGenresRequest, opts ...grpc.CallOption) (*ListGenresResponse, error) {
+	out := new(ListGenresResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/ListGenres", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) UpsertGenre(ctx context.Context, in *UpsertGenreRequest, opts ...grpc.CallOption) (*UpsertGenreResponse, error) {
+	out := new(UpsertGenreResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/UpsertGenre", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) FullTextSearch(ctx context.Context, in *FullTextSearchRequest, opts ...grpc.CallOption) (*FullTextSearchResponse, error) {
+	out := new(FullTextSearchResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/FullTextSearch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) FuzzyTitleLookup(ctx context.Context, in *FuzzyTitleLookupRequest, opts ...grpc.CallOption) (*FuzzyTitleLookupResponse, error) {
+	out := new(FuzzyTitleLookupResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/FuzzyTitleLookup", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) FindDuplicates(ctx context.Context, in *FindDuplicatesRequest, opts ...grpc.CallOption) (*FindDuplicatesResponse, error) {
+	out := new(FindDuplicatesResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/FindDuplicates", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) MergeMovies(ctx context.Context, in *MergeMoviesRequest, opts ...grpc.CallOption) (*MergeMoviesResponse, error) {
+	out := new(MergeMoviesResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/MergeMovies", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) GetMovieHistory(ctx context.Context, in *GetMovieHistoryRequest, opts ...grpc.CallOption) (*GetMovieHistoryResponse, error) {
+	out := new(GetMovieHistoryResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/GetMovieHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) ListSnapshots(ctx context.Context, in *ListSnapshotsRequest, opts ...grpc.CallOption) (*ListSnapshotsResponse, error) {
+	out := new(ListSnapshotsResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/ListSnapshots", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) RestoreSnapshot(ctx context.Context, in *RestoreSnapshotRequest, opts ...grpc.CallOption) (*RestoreSnapshotResponse, error) {
+	out := new(RestoreSnapshotResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/RestoreSnapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) WatchMovies(ctx context.Context, in *WatchMoviesRequest, opts ...grpc.CallOption) (MovieLibraryService_WatchMoviesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MovieLibraryService_ServiceDesc.Streams[2], "/movie_library.MovieLibraryService/WatchMovies", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &movieLibraryServiceWatchMoviesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MovieLibraryService_WatchMoviesClient interface {
+	Recv() (*MovieEvent, error)
+	grpc.ClientStream
+}
+
+type movieLibraryServiceWatchMoviesClient struct {
+	grpc.ClientStream
+}
+
+func (x *movieLibraryServiceWatchMoviesClient) Recv() (*MovieEvent, error) {
+	m := new(MovieEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *movieLibraryServiceClient) RegisterWebhook(ctx context.Context, in *RegisterWebhookRequest, opts ...grpc.CallOption) (*RegisterWebhookResponse, error) {
+	out := new(RegisterWebhookResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/RegisterWebhook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) ListWebhooks(ctx context.Context, in *ListWebhooksRequest, opts ...grpc.CallOption) (*ListWebhooksResponse, error) {
+	out := new(ListWebhooksResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/ListWebhooks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error) {
+	out := new(DeleteWebhookResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/DeleteWebhook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) ListWebhookDeliveries(ctx context.Context, in *ListWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ListWebhookDeliveriesResponse, error) {
+	out := new(ListWebhookDeliveriesResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/ListWebhookDeliveries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieLibraryServiceClient) BatchUpdateMovies(ctx context.Context, in *BatchUpdateMoviesRequest, opts ...grpc.CallOption) (*BatchUpdateMoviesResponse, error) {
+	out := new(BatchUpdateMoviesResponse)
+	err := c.cc.Invoke(ctx, "/movie_library.MovieLibraryService/BatchUpdateMovies", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MovieLibraryServiceServer is the server API for MovieLibraryService service.
 // All implementations must embed UnimplementedMovieLibraryServiceServer
 // for forward compatibility
@@ -69,6 +330,43 @@ type MovieLibraryServiceServer interface {
 	LoadMovies(context.Context, *MovieRequest) (*MovieResponse, error)
 	GetMovieDetails(context.Context, *GetMovieDetailsRequest) (*GetMovieDetailsResponse, error)
 	UpdateMovieDetails(context.Context, *UpdateMovieDetailsRequest) (*UpdateMovieDetailsResponse, error)
+	// UploadPoster stores the image for a movie. The first message carries the
+	// poster info, the following ones the image bytes.
+	UploadPoster(MovieLibraryService_UploadPosterServer) error
+	// GetPoster streams the poster info followed by the image bytes.
+	GetPoster(*GetPosterRequest, MovieLibraryService_GetPosterServer) error
+	// ListGenres returns the genre taxonomy.
+	ListGenres(context.Context, *ListGenresRequest) (*ListGenresResponse, error)
+	// UpsertGenre adds a genre or replaces the one with the same id.
+	UpsertGenre(context.Context, *UpsertGenreRequest) (*UpsertGenreResponse, error)
+	// FullTextSearch finds movies by words of their title or synopsis.
+	FullTextSearch(context.Context, *FullTextSearchRequest) (*FullTextSearchResponse, error)
+	// FuzzyTitleLookup finds movies whose title is close to the given one,
+	// tolerating typos, case and punctuation.
+	FuzzyTitleLookup(context.Context, *FuzzyTitleLookupRequest) (*FuzzyTitleLookupResponse, error)
+	// FindDuplicates groups movies that probably describe the same movie.
+	FindDuplicates(context.Context, *FindDuplicatesRequest) (*FindDuplicatesResponse, error)
+	// MergeMovies folds duplicates into a surviving movie and removes them.
+	MergeMovies(context.Context, *MergeMoviesRequest) (*MergeMoviesResponse, error)
+	// GetMovieHistory returns the audit log entries of a movie.
+	GetMovieHistory(context.Context, *GetMovieHistoryRequest) (*GetMovieHistoryResponse, error)
+	// ListSnapshots lists the saved versions of the library, newest first.
+	ListSnapshots(context.Context, *ListSnapshotsRequest) (*ListSnapshotsResponse, error)
+	// RestoreSnapshot replaces the library with a saved version. The library
+	// it replaces is snapshotted first, so a restore can be undone as well.
+	RestoreSnapshot(context.Context, *RestoreSnapshotRequest) (*RestoreSnapshotResponse, error)
+	// WatchMovies streams every change to the library as it happens.
+	WatchMovies(*WatchMoviesRequest, MovieLibraryService_WatchMoviesServer) error
+	// RegisterWebhook subscribes a URL to library changes. Every matching
+	// MovieEvent is POSTed to it as JSON, signed with the secret.
+	RegisterWebhook(context.Context, *RegisterWebhookRequest) (*RegisterWebhookResponse, error)
+	ListWebhooks(context.Context, *ListWebhooksRequest) (*ListWebhooksResponse, error)
+	DeleteWebhook(context.Context, *DeleteWebhookRequest) (*DeleteWebhookResponse, error)
+	// ListWebhookDeliveries returns the delivery log of a webhook.
+	ListWebhookDeliveries(context.Context, *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error)
+	// BatchUpdateMovies applies many updates with a single write of the
+	// library.
+	BatchUpdateMovies(context.Context, *BatchUpdateMoviesRequest) (*BatchUpdateMoviesResponse, error)
 	mustEmbedUnimplementedMovieLibraryServiceServer()
 }
 
@@ -85,6 +383,57 @@ func (UnimplementedMovieLibraryServiceServer) GetMovieDetails(context.Context, *
 func (UnimplementedMovieLibraryServiceServer) UpdateMovieDetails(context.Context, *UpdateMovieDetailsRequest) (*UpdateMovieDetailsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateMovieDetails not implemented")
 }
+func (UnimplementedMovieLibraryServiceServer) UploadPoster(MovieLibraryService_UploadPosterServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadPoster not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) GetPoster(*GetPosterRequest, MovieLibraryService_GetPosterServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetPoster not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) ListGenres(context.Context, *ListGenresRequest) (*ListGenresResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGenres not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) UpsertGenre(context.Context, *UpsertGenreRequest) (*UpsertGenreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpsertGenre not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) FullTextSearch(context.Context, *FullTextSearchRequest) (*FullTextSearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FullTextSearch not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) FuzzyTitleLookup(context.Context, *FuzzyTitleLookupRequest) (*FuzzyTitleLookupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FuzzyTitleLookup not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) FindDuplicates(context.Context, *FindDuplicatesRequest) (*FindDuplicatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindDuplicates not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) MergeMovies(context.Context, *MergeMoviesRequest) (*MergeMoviesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MergeMovies not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) GetMovieHistory(context.Context, *GetMovieHistoryRequest) (*GetMovieHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMovieHistory not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) ListSnapshots(context.Context, *ListSnapshotsRequest) (*ListSnapshotsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSnapshots not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) RestoreSnapshot(context.Context, *RestoreSnapshotRequest) (*RestoreSnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreSnapshot not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) WatchMovies(*WatchMoviesRequest, MovieLibraryService_WatchMoviesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchMovies not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) RegisterWebhook(context.Context, *RegisterWebhookRequest) (*RegisterWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterWebhook not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) ListWebhooks(context.Context, *ListWebhooksRequest) (*ListWebhooksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhooks not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) DeleteWebhook(context.Context, *DeleteWebhookRequest) (*DeleteWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhook not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) ListWebhookDeliveries(context.Context, *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhookDeliveries not implemented")
+}
+func (UnimplementedMovieLibraryServiceServer) BatchUpdateMovies(context.Context, *BatchUpdateMoviesRequest) (*BatchUpdateMoviesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchUpdateMovies not implemented")
+}
 func (UnimplementedMovieLibraryServiceServer) mustEmbedUnimplementedMovieLibraryServiceServer() {}
 
 // UnsafeMovieLibraryServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -152,6 +501,326 @@ func _MovieLibraryService_UpdateMovieDetails_Handler(srv interface{}, ctx contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MovieLibraryService_UploadPoster_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MovieLibraryServiceServer).UploadPoster(&movieLibraryServiceUploadPosterServer{stream})
+}
+
+type MovieLibraryService_UploadPosterServer interface {
+	SendAndClose(*UploadPosterResponse) error
+	Recv() (*UploadPosterRequest, error)
+	grpc.ServerStream
+}
+
+type movieLibraryServiceUploadPosterServer struct {
+	grpc.ServerStream
+}
+
+func (x *movieLibraryServiceUploadPosterServer) SendAndClose(m *UploadPosterResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *movieLibraryServiceUploadPosterServer) Recv() (*UploadPosterRequest, error) {
+	m := new(UploadPosterRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _MovieLibraryService_GetPoster_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetPosterRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MovieLibraryServiceServer).GetPoster(m, &movieLibraryServiceGetPosterServer{stream})
+}
+
+type MovieLibraryService_GetPosterServer interface {
+	Send(*GetPosterResponse) error
+	grpc.ServerStream
+}
+
+type movieLibraryServiceGetPosterServer struct {
+	grpc.ServerStream
+}
+
+func (x *movieLibraryServiceGetPosterServer) Send(m *GetPosterResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MovieLibraryService_ListGenres_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGenresRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).ListGenres(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/ListGenres",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).ListGenres(ctx, req.(*ListGenresRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_UpsertGenre_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpsertGenreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).UpsertGenre(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/UpsertGenre",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).UpsertGenre(ctx, req.(*UpsertGenreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_FullTextSearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FullTextSearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).FullTextSearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/FullTextSearch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).FullTextSearch(ctx, req.(*FullTextSearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_FuzzyTitleLookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FuzzyTitleLookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).FuzzyTitleLookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/FuzzyTitleLookup",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).FuzzyTitleLookup(ctx, req.(*FuzzyTitleLookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_FindDuplicates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindDuplicatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).FindDuplicates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/FindDuplicates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).FindDuplicates(ctx, req.(*FindDuplicatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_MergeMovies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeMoviesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).MergeMovies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/MergeMovies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).MergeMovies(ctx, req.(*MergeMoviesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_GetMovieHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMovieHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).GetMovieHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/GetMovieHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).GetMovieHistory(ctx, req.(*GetMovieHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_ListSnapshots_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSnapshotsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).ListSnapshots(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/ListSnapshots",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).ListSnapshots(ctx, req.(*ListSnapshotsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_RestoreSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreSnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).RestoreSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/RestoreSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).RestoreSnapshot(ctx, req.(*RestoreSnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_WatchMovies_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchMoviesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MovieLibraryServiceServer).WatchMovies(m, &movieLibraryServiceWatchMoviesServer{stream})
+}
+
+type MovieLibraryService_WatchMoviesServer interface {
+	Send(*MovieEvent) error
+	grpc.ServerStream
+}
+
+type movieLibraryServiceWatchMoviesServer struct {
+	grpc.ServerStream
+}
+
+func (x *movieLibraryServiceWatchMoviesServer) Send(m *MovieEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MovieLibraryService_RegisterWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).RegisterWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/RegisterWebhook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).RegisterWebhook(ctx, req.(*RegisterWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_ListWebhooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhooksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).ListWebhooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/ListWebhooks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).ListWebhooks(ctx, req.(*ListWebhooksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_DeleteWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).DeleteWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/DeleteWebhook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).DeleteWebhook(ctx, req.(*DeleteWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_ListWebhookDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhookDeliveriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).ListWebhookDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/ListWebhookDeliveries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).ListWebhookDeliveries(ctx, req.(*ListWebhookDeliveriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieLibraryService_BatchUpdateMovies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchUpdateMoviesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieLibraryServiceServer).BatchUpdateMovies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/movie_library.MovieLibraryService/BatchUpdateMovies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieLibraryServiceServer).BatchUpdateMovies(ctx, req.(*BatchUpdateMoviesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MovieLibraryService_ServiceDesc is the grpc.ServiceDesc for MovieLibraryService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -171,7 +840,79 @@ var MovieLibraryService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateMovieDetails",
 			Handler:    _MovieLibraryService_UpdateMovieDetails_Handler,
 		},
+		{
+			MethodName: "ListGenres",
+			Handler:    _MovieLibraryService_ListGenres_Handler,
+		},
+		{
+			MethodName: "UpsertGenre",
+			Handler:    _MovieLibraryService_UpsertGenre_Handler,
+		},
+		{
+			MethodName: "FullTextSearch",
+			Handler:    _MovieLibraryService_FullTextSearch_Handler,
+		},
+		{
+			MethodName: "FuzzyTitleLookup",
+			Handler:    _MovieLibraryService_FuzzyTitleLookup_Handler,
+		},
+		{
+			MethodName: "FindDuplicates",
+			Handler:    _MovieLibraryService_FindDuplicates_Handler,
+		},
+		{
+			MethodName: "MergeMovies",
+			Handler:    _MovieLibraryService_MergeMovies_Handler,
+		},
+		{
+			MethodName: "GetMovieHistory",
+			Handler:    _MovieLibraryService_GetMovieHistory_Handler,
+		},
+		{
+			MethodName: "ListSnapshots",
+			Handler:    _MovieLibraryService_ListSnapshots_Handler,
+		},
+		{
+			MethodName: "RestoreSnapshot",
+			Handler:    _MovieLibraryService_RestoreSnapshot_Handler,
+		},
+		{
+			MethodName: "RegisterWebhook",
+			Handler:    _MovieLibraryService_RegisterWebhook_Handler,
+		},
+		{
+			MethodName: "ListWebhooks",
+			Handler:    _MovieLibraryService_ListWebhooks_Handler,
+		},
+		{
+			MethodName: "DeleteWebhook",
+			Handler:    _MovieLibraryService_DeleteWebhook_Handler,
+		},
+		{
+			MethodName: "ListWebhookDeliveries",
+			Handler:    _MovieLibraryService_ListWebhookDeliveries_Handler,
+		},
+		{
+			MethodName: "BatchUpdateMovies",
+			Handler:    _MovieLibraryService_BatchUpdateMovies_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadPoster",
+			Handler:       _MovieLibraryService_UploadPoster_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "GetPoster",
+			Handler:       _MovieLibraryService_GetPoster_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchMovies",
+			Handler:       _MovieLibraryService_WatchMovies_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "movie.proto",
 }
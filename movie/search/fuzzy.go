@@ -0,0 +1,92 @@
+package search
+
+import (
+	"sort"
+	"strings"
+)
+
+// TitleMatch is a title similar to the one looked up.
+type TitleMatch struct {
+	ID    int32
+	Title string
+	Score float64 // 1 for equal titles, down to 0
+}
+
+// normalizeTitle folds title and joins its words with single spaces, so
+// punctuation and case do not count as edits.
+func normalizeTitle(title string) []rune {
+	tokens := Tokenize(title)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.Term
+	}
+	return []rune(strings.Join(terms, " "))
+}
+
+// TitleSimilarity compares two titles by edit distance after folding, as 1
+// minus the distance over the length of the longer title.
+func TitleSimilarity(a, b string) float64 {
+	ra, rb := normalizeTitle(a), normalizeTitle(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein counts the insertions, deletions and substitutions that turn
+// a into b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// FuzzyTitles returns the documents whose title scores at least threshold
+// against title with TitleSimilarity, best first. At most limit matches are
+// returned, all of them when limit is 0.
+func (ix *Index) FuzzyTitles(title string, threshold float64, limit int) []TitleMatch {
+	var matches []TitleMatch
+	for _, d := range ix.docs {
+		if score := TitleSimilarity(title, d.Title); score >= threshold && score > 0 {
+			matches = append(matches, TitleMatch{ID: d.ID, Title: d.Title, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
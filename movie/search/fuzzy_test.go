@@ -0,0 +1,89 @@
+package search
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "sholay", 6},
+		{"sholay", "sholay", 0},
+		{"sholay", "sholey", 1},
+		{"sholay", "shole", 2},
+		{"kitten", "sitting", 3},
+		{"deewar", "deewaar", 1},
+		{"शोले", "शोला", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := levenshtein([]rune(tt.b), []rune(tt.a)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Sholay", "sholay", 1},
+		{"Amélie", "AMELIE!", 1},
+		{"Paris,  je t'aime", "paris je t aime", 1},
+		{"Sholay", "Sholey", 1 - 1.0/6},
+		{"Deewar", "Deewaar", 1 - 1.0/7},
+		{"Sholay", "Lagaan", 1 - 5.0/6},
+		{"", "Sholay", 0},
+		{"!!", "", 0},
+	}
+	for _, tt := range tests {
+		if got := TitleSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("TitleSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyTitles(t *testing.T) {
+	ix := NewIndex([]Document{
+		{ID: 1, Title: "Sholay"},
+		{ID: 2, Title: "Shole"},
+		{ID: 3, Title: "Sholay"},
+		{ID: 4, Title: "Lagaan"},
+		{ID: 5, Title: "Shola Aur Shabnam"},
+		{ID: 6, Title: "Sholey"},
+	})
+	tests := []struct {
+		title     string
+		threshold float64
+		limit     int
+		want      []int32
+	}{
+		// Best first, equal scores by ID.
+		{"sholay", 0.6, 0, []int32{1, 3, 6, 2}},
+		// The cutoff is inclusive: Shole scores exactly 4/6.
+		{"sholay", 4.0 / 6, 0, []int32{1, 3, 6, 2}},
+		{"sholay", 0.7, 0, []int32{1, 3, 6}},
+		{"sholay", 1, 0, []int32{1, 3}},
+		{"sholay", 0.6, 2, []int32{1, 3}},
+		{"shola aur shabnam", 0.8, 0, []int32{5}},
+		// A threshold of 0 still leaves out titles with nothing in common.
+		{"xyz", 0, 0, nil},
+	}
+	for _, tt := range tests {
+		var got []int32
+		for _, m := range ix.FuzzyTitles(tt.title, tt.threshold, tt.limit) {
+			got = append(got, m.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FuzzyTitles(%q, %v, %d) = %v, want %v", tt.title, tt.threshold, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package search is an in-memory full-text index over movie titles and
+// synopses. It supports ranked queries with prefix matching, folds case and
+// diacritics, and tokenizes any script Unicode knows letters for, so Hindi
+// titles are searchable too.
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Field weights: a match in the title counts more than one in the synopsis.
+const (
+	titleWeight    = 3.0
+	synopsisWeight = 1.0
+
+	// prefixFactor scales the score of terms that only match as a prefix.
+	prefixFactor = 0.5
+
+	// snippetWords is the number of words shown around a synopsis match.
+	snippetWords = 20
+)
+
+// Document is a movie as indexed.
+type Document struct {
+	ID       int32
+	Title    string
+	Synopsis string
+}
+
+// Hit is a matching document. Title and Snippet are HTML-escaped, with the
+// matching words wrapped in <mark>; Snippet is empty when only the title
+// matched.
+type Hit struct {
+	ID      int32
+	Score   float64
+	Title   string
+	Snippet string
+}
+
+type posting struct {
+	doc      int // index into Index.docs
+	title    int // term frequency in the title
+	synopsis int // term frequency in the synopsis
+}
+
+// Index is an immutable inverted index; build a new one when the documents
+// change.
+type Index struct {
+	docs     []Document
+	postings map[string][]posting
+	terms    []string // sorted keys of postings, for prefix lookups
+}
+
+// NewIndex indexes docs.
+func NewIndex(docs []Document) *Index {
+	ix := &Index{docs: docs, postings: map[string][]posting{}}
+	for i, d := range docs {
+		counts := map[string]*posting{}
+		count := func(text string, title bool) {
+			for _, t := range Tokenize(text) {
+				p := counts[t.Term]
+				if p == nil {
+					p = &posting{doc: i}
+					counts[t.Term] = p
+				}
+				if title {
+					p.title++
+				} else {
+					p.synopsis++
+				}
+			}
+		}
+		count(d.Title, true)
+		count(d.Synopsis, false)
+		for term, p := range counts {
+			ix.postings[term] = append(ix.postings[term], *p)
+		}
+	}
+	for term := range ix.postings {
+		ix.terms = append(ix.terms, term)
+	}
+	sort.Strings(ix.terms)
+	return ix
+}
+
+// Len returns the number of indexed documents.
+func (ix *Index) Len() int {
+	return len(ix.docs)
+}
+
+// expand returns the index terms that start with term.
+func (ix *Index) expand(term string) []string {
+	var terms []string
+	for i := sort.SearchStrings(ix.terms, term); i < len(ix.terms) && strings.HasPrefix(ix.terms[i], term); i++ {
+		terms = append(terms, ix.terms[i])
+	}
+	return terms
+}
+
+// Search returns the documents that contain every word of query, either
+// whole or as a prefix, best first. Scores are TF-IDF sums weighted by
+// field. At most limit hits are returned, all of them when limit is 0;
+// total is the number of matching documents.
+func (ix *Index) Search(query string, limit int) (hits []Hit, total int) {
+	words := Tokenize(query)
+	if len(words) == 0 {
+		return nil, 0
+	}
+
+	scores := map[int]float64{}
+	matched := map[int]map[string]bool{} // terms found per document
+	for n, w := range words {
+		found := map[int]float64{}
+		for _, term := range ix.expand(w.Term) {
+			factor := 1.0
+			if term != w.Term {
+				factor = prefixFactor
+			}
+			postings := ix.postings[term]
+			idf := math.Log(1 + float64(len(ix.docs))/float64(len(postings)))
+			for _, p := range postings {
+				score := factor * idf * (titleWeight*float64(p.title) + synopsisWeight*float64(p.synopsis))
+				if score > found[p.doc] {
+					found[p.doc] = score
+				}
+				if matched[p.doc] == nil {
+					matched[p.doc] = map[string]bool{}
+				}
+				matched[p.doc][term] = true
+			}
+		}
+		// Every word must match: keep only documents found so far.
+		for doc, score := range found {
+			if n == 0 {
+				scores[doc] = score
+			} else if _, ok := scores[doc]; ok {
+				scores[doc] += score
+			}
+		}
+		if n > 0 {
+			for doc := range scores {
+				if _, ok := found[doc]; !ok {
+					delete(scores, doc)
+				}
+			}
+		}
+	}
+
+	docs := make([]int, 0, len(scores))
+	for doc := range scores {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if scores[docs[i]] != scores[docs[j]] {
+			return scores[docs[i]] > scores[docs[j]]
+		}
+		return ix.docs[docs[i]].ID < ix.docs[docs[j]].ID
+	})
+	total = len(docs)
+	if limit > 0 && len(docs) > limit {
+		docs = docs[:limit]
+	}
+
+	for _, doc := range docs {
+		d := ix.docs[doc]
+		hits = append(hits, Hit{
+			ID:      d.ID,
+			Score:   scores[doc],
+			Title:   highlight(d.Title, matched[doc], 0),
+			Snippet: highlight(d.Synopsis, matched[doc], snippetWords),
+		})
+	}
+	return hits, total
+}
+
+// highlight escapes text and marks the words whose terms are in terms.
+// With window > 0 only about window words around the first match are kept,
+// and nothing is returned when no word matches.
+func highlight(text string, terms map[string]bool, window int) string {
+	tokens := Tokenize(text)
+	first := -1
+	for i, t := range tokens {
+		if terms[t.Term] {
+			first = i
+			break
+		}
+	}
+	if window > 0 && first < 0 {
+		return ""
+	}
+
+	from, to := 0, len(tokens)
+	start, end := 0, len(text)
+	if window > 0 {
+		from = first - window/4
+		if from < 0 {
+			from = 0
+		}
+		to = from + window
+		if to > len(tokens) {
+			to = len(tokens)
+		}
+		if from > 0 {
+			start = tokens[from].Start
+		}
+		if to < len(tokens) {
+			end = tokens[to-1].End
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("… ")
+	}
+	pos := start
+	for _, t := range tokens[from:to] {
+		if !terms[t.Term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:t.Start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[t.Start:t.End]))
+		b.WriteString("</mark>")
+		pos = t.End
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString(" …")
+	}
+	return b.String()
+}
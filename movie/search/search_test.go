@@ -0,0 +1,116 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+// corpus is a small fixed set of documents for the search tests.
+var corpus = []Document{
+	{ID: 1, Title: "Sholay", Synopsis: "Two convicts are hired by a retired policeman to capture a ruthless dacoit."},
+	{ID: 2, Title: "Amélie", Synopsis: "A shy waitress in Paris decides to change the lives of those around her."},
+	{ID: 3, Title: "Lagaan", Synopsis: "Villagers stake their taxes on a game of cricket against the British."},
+	{ID: 4, Title: "Deewaar", Synopsis: "A police officer confronts his brother, a smuggler."},
+	{ID: 5, Title: "Paris, je t'aime", Synopsis: "Eighteen stories of love in Paris."},
+	{ID: 6, Title: "शोले", Synopsis: "दो अपराधी एक डाकू को पकड़ते हैं।"},
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Token
+	}{
+		{"", nil},
+		{" -- ", nil},
+		{"Paris, je t'aime", []Token{{"paris", 0, 5}, {"je", 7, 9}, {"t", 10, 11}, {"aime", 12, 16}}},
+		{"AMÉLIE (2001)", []Token{{"amelie", 0, 7}, {"2001", 9, 13}}},
+		{"शोले, दीवार", []Token{{"शोले", 0, 12}, {"दीवार", 14, 29}}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Amélie", "amelie"},
+		{"AMÉLIE", "amelie"},
+		{"Ångström", "angstrom"},
+		{"Ёлка", "елка"},
+		{"शोले", "शोले"}, // vowel signs are kept
+		{"क्‍ष", "क्ष"},  // joiners are dropped
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ix := NewIndex(corpus)
+	tests := []struct {
+		query string
+		want  []int32
+	}{
+		{"", nil},
+		{"western", nil},
+		// A match in the title outweighs one in the synopsis.
+		{"paris", []int32{5, 2}},
+		// Whole words score above prefixes: "police" before "policeman".
+		{"police", []int32{4, 1}},
+		{"pol", []int32{1, 4}},
+		{"AMELIE", []int32{2}},
+		{"शोले", []int32{6}},
+		// Every word must match.
+		{"paris love", []int32{5}},
+		{"paris cricket", nil},
+	}
+	for _, tt := range tests {
+		hits, total := ix.Search(tt.query, 0)
+		var got []int32
+		for _, h := range hits {
+			got = append(got, h.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) || total != len(tt.want) {
+			t.Errorf("Search(%q) = %v (total %d), want %v", tt.query, got, total, tt.want)
+		}
+	}
+
+	hits, total := ix.Search("paris", 1)
+	if len(hits) != 1 || hits[0].ID != 5 || total != 2 {
+		t.Errorf("Search(paris, 1) = %+v (total %d), want document 5 of 2", hits, total)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	ix := NewIndex(corpus)
+	tests := []struct {
+		query       string
+		title, snip string
+	}{
+		{"paris", "<mark>Paris</mark>, je t&#39;aime", "Eighteen stories of love in <mark>Paris</mark>."},
+		{"amelie", "<mark>Amélie</mark>", ""},
+		{"polic", "Sholay", "… are hired by a retired <mark>policeman</mark> to capture a ruthless dacoit."},
+	}
+	for _, tt := range tests {
+		hits, _ := ix.Search(tt.query, 1)
+		if len(hits) != 1 {
+			t.Errorf("Search(%q): %d hits", tt.query, len(hits))
+			continue
+		}
+		if hits[0].Title != tt.title || hits[0].Snippet != tt.snip {
+			t.Errorf("Search(%q) = %q, %q, want %q, %q", tt.query, hits[0].Title, hits[0].Snippet, tt.title, tt.snip)
+		}
+	}
+
+	// Snippets keep a window of words starting a quarter of it before the
+	// first match.
+	text := "one two three four five six seven eight nine ten eleven twelve"
+	got := highlight(text, map[string]bool{"seven": true}, 4)
+	if want := "… six <mark>seven</mark> eight nine …"; got != want {
+		t.Errorf("highlight = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Token is a word of a text, with its byte offsets in the original string.
+type Token struct {
+	Term       string // folded form used for lookups
+	Start, End int
+}
+
+// isWordRune reports whether r belongs to a word. Combining marks are part
+// of words, which keeps Devanagari vowel signs and viramas with their
+// consonants; zero-width joiners may appear inside Indic words.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.M, r) || r == '\u200c' || r == '\u200d'
+}
+
+// Tokenize splits s into words and folds each with Fold.
+func Tokenize(s string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range s {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = appendToken(tokens, s, start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = appendToken(tokens, s, start, len(s))
+	}
+	return tokens
+}
+
+func appendToken(tokens []Token, s string, start, end int) []Token {
+	if term := Fold(s[start:end]); term != "" {
+		tokens = append(tokens, Token{Term: term, Start: start, End: end})
+	}
+	return tokens
+}
+
+// Fold lower-cases s and removes diacritics from Latin, Greek and Cyrillic
+// letters, so "Amélie" and "amelie" are the same term. Marks on other
+// scripts carry meaning, e.g. Devanagari vowel signs, and are kept.
+func Fold(s string) string {
+	var b strings.Builder
+	var base rune
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		if r == '\u200c' || r == '\u200d' {
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) && (unicode.In(base, unicode.Latin, unicode.Greek, unicode.Cyrillic)) {
+			continue
+		}
+		if !unicode.Is(unicode.M, r) {
+			base = r
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
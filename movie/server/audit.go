@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys the gateway uses to pass on who made a request.
+const (
+	actorKey     = "x-actor"
+	requestIDKey = "x-request-id"
+)
+
+// Audit operations.
+const (
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+)
+
+// maxAuditLine bounds a single audit entry when reading the log back.
+const maxAuditLine = 16 << 20
+
+// auditEntry records one change to one movie.
+type auditEntry struct {
+	Time      time.Time             `json:"time"`
+	Actor     string                `json:"actor,omitempty"`
+	RequestID string                `json:"requestId,omitempty"`
+	Action    string                `json:"action"` // RPC method, or "reload" for outside edits of the file
+	Op        string                `json:"op"`     // create, update or delete
+	MovieID   int32                 `json:"movieId"`
+	Before    *catalog.Movie        `json:"before,omitempty"`
+	After     *catalog.Movie        `json:"after,omitempty"`
+	Changes   []catalog.FieldChange `json:"changes,omitempty"`
+}
+
+// auditLog is an append-only JSON Lines file of auditEntry values.
+type auditLog struct {
+	mu   sync.Mutex
+	path string
+}
+
+// append writes entries and syncs the file before returning.
+func (a *auditLog) append(ctx context.Context, entries []auditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	_, span := tracer.Start(ctx, "audit.append")
+	defer span.End()
+	span.SetAttributes(attribute.Int("audit.entries", len(entries)))
+
+	var buf []byte
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// history returns the entries of movieID in the order they were written,
+// at most the last limit of them when limit is positive.
+func (a *auditLog) history(ctx context.Context, movieID int32, limit int) ([]auditEntry, error) {
+	_, span := tracer.Start(ctx, "audit.read")
+	defer span.End()
+
+	f, err := os.Open(a.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []auditEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, maxAuditLine)
+	for sc.Scan() {
+		var e auditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, err
+		}
+		if e.MovieID != movieID {
+			continue
+		}
+		entries = append(entries, e)
+		if limit > 0 && len(entries) > limit {
+			entries = entries[1:]
+		}
+	}
+	return entries, sc.Err()
+}
+
+// requestInfo returns the action, actor and request ID of the RPC in ctx.
+// Requests without an ID get a random one.
+func requestInfo(ctx context.Context) (action, actor, requestID string) {
+	if method, ok := grpc.Method(ctx); ok {
+		action = path.Base(method)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(actorKey); len(v) > 0 {
+		actor = v[0]
+	}
+	if v := md.Get(requestIDKey); len(v) > 0 {
+		requestID = v[0]
+	} else {
+		b := make([]byte, 8)
+		rand.Read(b)
+		requestID = hex.EncodeToString(b)
+	}
+	return action, actor, requestID
+}
+
+// diffLibraries returns an unstamped entry for every movie created, updated
+// or deleted between before and after, matching movies by ID.
+func diffLibraries(before, after []catalog.Movie) []auditEntry {
+	old := map[int32]catalog.Movie{}
+	for _, m := range before {
+		old[m.ID] = m
+	}
+	kept := map[int32]bool{}
+
+	var entries []auditEntry
+	for i := range after {
+		m := after[i]
+		kept[m.ID] = true
+		o, ok := old[m.ID]
+		if !ok {
+			entries = append(entries, auditEntry{Op: opCreate, MovieID: m.ID, After: &m})
+			continue
+		}
+		if changes := movieChanges(o, m); len(changes) > 0 {
+			entries = append(entries, auditEntry{Op: opUpdate, MovieID: m.ID, Before: &o, After: &m, Changes: changes})
+		}
+	}
+	for i := range before {
+		o := before[i]
+		if !kept[o.ID] {
+			entries = append(entries, auditEntry{Op: opDelete, MovieID: o.ID, Before: &o})
+		}
+	}
+	return entries
+}
+
+// movieChanges returns the catalog.Changes between a and b, followed by the
+// poster when that changed too.
+func movieChanges(a, b catalog.Movie) []catalog.FieldChange {
+	changes := catalog.Changes(a, b)
+	if a.PosterURL != b.PosterURL {
+		changes = append(changes, catalog.FieldChange{Field: "posterUrl", Old: a.PosterURL, New: b.PosterURL})
+	}
+	return changes
+}
+
+// stamp fills in the time and request details of entries.
+func stamp(entries []auditEntry, action, actor, requestID string) {
+	now := time.Now().UTC()
+	for i := range entries {
+		entries[i].Time = now
+		entries[i].Action = action
+		entries[i].Actor = actor
+		entries[i].RequestID = requestID
+	}
+}
+
+func auditEntryToProto(e auditEntry) *pb.AuditEntry {
+	p := &pb.AuditEntry{
+		TimeUnix:  e.Time.Unix(),
+		Actor:     e.Actor,
+		RequestId: e.RequestID,
+		Action:    e.Action,
+		Op:        e.Op,
+		MovieId:   e.MovieID,
+	}
+	if e.Before != nil {
+		p.Before = e.Before.ToProto()
+	}
+	if e.After != nil {
+		p.After = e.After.ToProto()
+	}
+	for _, c := range e.Changes {
+		p.Changes = append(p.Changes, &pb.FieldChange{Field: c.Field, Old: c.Old, New: c.New})
+	}
+	return p
+}
+
+func (s *movieLibraryServer) GetMovieHistory(ctx context.Context, req *pb.GetMovieHistoryRequest) (*pb.GetMovieHistoryResponse, error) {
+	if req.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+	entries, err := s.audit.history(ctx, req.GetMovieId(), int(req.GetLimit()))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "read audit log: %v", err)
+	}
+	resp := &pb.GetMovieHistoryResponse{}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, auditEntryToProto(e))
+	}
+	return resp, nil
+}
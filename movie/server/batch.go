@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatchUpdates bounds the updates of a BatchUpdateMovies call.
+const maxBatchUpdates = 10000
+
+// errBatchFailed aborts the library change of an all-or-nothing batch.
+var errBatchFailed = errors.New("batch failed")
+
+// applyUpdate updates the movie with id in movies and returns the result.
+// With a mask only the named fields are taken from patch; without one patch
+// replaces the movie. The movie keeps its ID and poster either way, and
+// movies is left alone when the result fails catalog.Movie.Validate. Unless
+// expected is empty, the movie must be at one of its versions.
+func (s *movieLibraryServer) applyUpdate(movies []catalog.Movie, id int32, patch catalog.Movie, mask []string, expected []int64) (catalog.Movie, error) {
+	idx := indexOf(movies, id)
+	if idx < 0 {
+		return catalog.Movie{}, status.Errorf(codes.NotFound, "movie %d not found", id)
+	}
+	current := movies[idx]
+	if !versionMatches(current.Version, expected) {
+		return catalog.Movie{}, status.Errorf(codes.FailedPrecondition, "movie %d is at version %d, not %s", id, current.Version, joinVersions(expected))
+	}
+
+	updated := patch
+	if len(mask) > 0 {
+		updated = current
+		for _, name := range mask {
+			f, ok := catalog.FieldByName(name)
+			if !ok {
+				return catalog.Movie{}, status.Errorf(codes.InvalidArgument, "unknown field %q in update mask", name)
+			}
+			f.Copy(&updated, patch)
+		}
+	}
+	if err := s.normalizeMovie(&updated); err != nil {
+		return catalog.Movie{}, err
+	}
+	if err := updated.Validate(); err != nil {
+		return catalog.Movie{}, status.Errorf(codes.InvalidArgument, "movie %d: %v", id, err)
+	}
+	updated.ID = current.ID
+	updated.PosterURL = current.PosterURL
+	updated.Version = nextVersion(current, updated)
+	movies[idx] = updated
+	return updated, nil
+}
+
+// expectedVersions returns the non-zero versions among version and
+// versions.
+func expectedVersions(version int64, versions ...int64) []int64 {
+	var expected []int64
+	for _, v := range append([]int64{version}, versions...) {
+		if v != 0 {
+			expected = append(expected, v)
+		}
+	}
+	return expected
+}
+
+// versionMatches reports whether version is one of expected, or expected
+// is empty.
+func versionMatches(version int64, expected []int64) bool {
+	for _, v := range expected {
+		if v == version {
+			return true
+		}
+	}
+	return len(expected) == 0
+}
+
+func joinVersions(versions []int64) string {
+	s := make([]string, len(versions))
+	for i, v := range versions {
+		s[i] = strconv.FormatInt(v, 10)
+	}
+	return strings.Join(s, " or ")
+}
+
+// batchStatus maps the error of an update to the status code of its result.
+func batchStatus(err error) int32 {
+	switch status.Code(err) {
+	case codes.OK:
+		return http.StatusOK
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *movieLibraryServer) BatchUpdateMovies(ctx context.Context, req *pb.BatchUpdateMoviesRequest) (*pb.BatchUpdateMoviesResponse, error) {
+	if len(req.GetUpdates()) > maxBatchUpdates {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d updates per batch", maxBatchUpdates)
+	}
+	// The results report each movie as its update left it, so a movie may
+	// only be updated once per batch.
+	seen := map[int32]bool{}
+	for _, u := range req.GetUpdates() {
+		if seen[u.GetMovieId()] {
+			return nil, status.Errorf(codes.InvalidArgument, "movie %d is updated more than once", u.GetMovieId())
+		}
+		seen[u.GetMovieId()] = true
+	}
+
+	var results []*pb.MovieUpdateResult
+	failed := false
+	err := s.modifyLibrary(ctx, func(movies []catalog.Movie) ([]catalog.Movie, error) {
+		results, failed = make([]*pb.MovieUpdateResult, len(req.GetUpdates())), false
+		for i, u := range req.GetUpdates() {
+			patch := catalog.FromProto(u.GetMovie())
+			updated, err := s.applyUpdate(movies, u.GetMovieId(), patch, u.GetUpdateMask(), expectedVersions(u.GetExpectedVersion()))
+			results[i] = &pb.MovieUpdateResult{MovieId: u.GetMovieId(), StatusCode: batchStatus(err)}
+			if err != nil {
+				results[i].Error = status.Convert(err).Message()
+				failed = true
+				continue
+			}
+			results[i].Movie = updated.ToProto()
+		}
+		if failed && req.GetAllOrNothing() {
+			return nil, errBatchFailed
+		}
+		return movies, nil
+	})
+	if err != nil && err != errBatchFailed {
+		return nil, err
+	}
+
+	resp := &pb.BatchUpdateMoviesResponse{StatusCode: http.StatusOK, Results: results}
+	if failed {
+		resp.StatusCode = http.StatusMultiStatus
+		if req.GetAllOrNothing() {
+			for _, r := range results {
+				if r.StatusCode == http.StatusOK {
+					r.StatusCode, r.Movie = http.StatusFailedDependency, nil
+					r.Error = "not applied: another update of the batch failed"
+				}
+			}
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+
+	pb "movie/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// runtimeUpdate sets the runtime of the movie with id.
+func runtimeUpdate(id, runtime int32, expectedVersion int64) *pb.MovieUpdate {
+	return &pb.MovieUpdate{
+		MovieId:         id,
+		Movie:           &pb.Movie{RuntimeMinutes: runtime},
+		UpdateMask:      []string{"runtimeMinutes"},
+		ExpectedVersion: expectedVersion,
+	}
+}
+
+// resultCodes returns the status codes of the results by movie ID.
+func resultCodes(resp *pb.BatchUpdateMoviesResponse) map[int32]int32 {
+	m := map[int32]int32{}
+	for _, r := range resp.Results {
+		m[r.MovieId] = r.StatusCode
+	}
+	return m
+}
+
+func runtimes(s *movieLibraryServer) map[int32]int32 {
+	m := map[int32]int32{}
+	for _, movie := range s.lib.all() {
+		m[movie.ID] = movie.RuntimeMinutes
+	}
+	return m
+}
+
+func TestBatchUpdate(t *testing.T) {
+	s := newTestServer(t)
+	load(t, s, sholay, deewar, lagaan)
+
+	resp, err := s.BatchUpdateMovies(context.Background(), &pb.BatchUpdateMoviesRequest{
+		Updates: []*pb.MovieUpdate{
+			runtimeUpdate(1, 204, 1),
+			runtimeUpdate(2, 174, 0),
+			runtimeUpdate(3, 224, 7), // stale
+			runtimeUpdate(9, 100, 0), // unknown
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Errorf("status %d, want %d", resp.StatusCode, http.StatusMultiStatus)
+	}
+	want := map[int32]int32{1: http.StatusOK, 2: http.StatusOK, 3: http.StatusPreconditionFailed, 9: http.StatusNotFound}
+	if got := resultCodes(resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("results %v, want %v", got, want)
+	}
+	if m := resp.Results[0].Movie; m.GetRuntimeMinutes() != 204 || m.GetVersion() != 2 || m.GetTitle() != "Sholay" {
+		t.Errorf("result movie %v, want Sholay at version 2 with a runtime", m)
+	}
+	if got, want := runtimes(s), map[int32]int32{1: 204, 2: 174, 3: 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("runtimes %v, want %v", got, want)
+	}
+}
+
+func TestBatchUpdateAllOrNothing(t *testing.T) {
+	s := newTestServer(t)
+	load(t, s, sholay, deewar, lagaan)
+	before, err := os.ReadFile(s.libraryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.BatchUpdateMovies(context.Background(), &pb.BatchUpdateMoviesRequest{
+		Updates: []*pb.MovieUpdate{
+			runtimeUpdate(1, 204, 0),
+			{MovieId: 2, Movie: &pb.Movie{ReleaseDate: "1975-01-24"}, UpdateMask: []string{"releaseDate"}},
+			runtimeUpdate(3, 224, 1),
+		},
+		AllOrNothing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int32]int32{1: http.StatusFailedDependency, 2: http.StatusBadRequest, 3: http.StatusFailedDependency}
+	if got := resultCodes(resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("results %v, want %v", got, want)
+	}
+	for _, r := range resp.Results {
+		if r.Movie != nil || r.Error == "" {
+			t.Errorf("result %v, want an error and no movie", r)
+		}
+	}
+
+	// Nothing was applied: not in memory, not on disk, no new versions.
+	if got, want := runtimes(s), map[int32]int32{1: 0, 2: 0, 3: 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("runtimes %v, want %v", got, want)
+	}
+	for _, m := range s.lib.all() {
+		if m.Version != 1 {
+			t.Errorf("movie %d at version %d, want 1", m.ID, m.Version)
+		}
+	}
+	after, err := os.ReadFile(s.libraryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Error("library file rewritten by a failed batch")
+	}
+}
+
+func TestBatchUpdateRejected(t *testing.T) {
+	s := newTestServer(t)
+	load(t, s, sholay, deewar)
+
+	_, err := s.BatchUpdateMovies(context.Background(), &pb.BatchUpdateMoviesRequest{
+		Updates: []*pb.MovieUpdate{runtimeUpdate(1, 204, 0), runtimeUpdate(2, 174, 0), runtimeUpdate(1, 198, 0)},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("movie updated twice: %v, want InvalidArgument", err)
+	}
+	if got, want := runtimes(s), map[int32]int32{1: 0, 2: 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("runtimes %v, want %v", got, want)
+	}
+
+	many := make([]*pb.MovieUpdate, maxBatchUpdates+1)
+	for i := range many {
+		many[i] = runtimeUpdate(int32(i+1), 100, 0)
+	}
+	if _, err := s.BatchUpdateMovies(context.Background(), &pb.BatchUpdateMoviesRequest{Updates: many}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("%d updates: %v, want InvalidArgument", len(many), err)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"movie/telemetry"
+)
+
+// Config holds the settings of the gRPC server.
+type Config struct {
+	Addr           string        `yaml:"addr" env:"MOVIE_SERVER_ADDR" flag:"addr" usage:"gRPC listen address"`
+	LibraryPath    string        `yaml:"library_path" env:"JSON_FILE_PATH" flag:"library" usage:"path of the JSON library file"`
+	WatchInterval  time.Duration `yaml:"watch_interval" env:"MOVIE_WATCH_INTERVAL" flag:"watch-interval" usage:"how often to check the library file for outside changes, 0 to disable"`
+	PosterDir      string        `yaml:"poster_dir" env:"MOVIE_POSTER_DIR" flag:"poster-dir" usage:"directory poster images are stored in"`
+	MaxPosterBytes int           `yaml:"max_poster_bytes" env:"MOVIE_MAX_POSTER_BYTES" flag:"max-poster-bytes" usage:"largest accepted poster upload in bytes"`
+	GenresPath     string        `yaml:"genres_path" env:"MOVIE_GENRES_PATH" flag:"genres" usage:"path of the JSON genre taxonomy"`
+	StrictGenres   bool          `yaml:"strict_genres" env:"MOVIE_STRICT_GENRES" flag:"strict-genres" usage:"reject movies with genres missing from the taxonomy"`
+	AuditPath      string        `yaml:"audit_path" env:"MOVIE_AUDIT_PATH" flag:"audit-log" usage:"path of the append-only JSON Lines audit log"`
+	SnapshotDir    string        `yaml:"snapshot_dir" env:"MOVIE_SNAPSHOT_DIR" flag:"snapshot-dir" usage:"directory the library is snapshotted to before every load or restore"`
+	SnapshotKeep   int           `yaml:"snapshot_keep" env:"MOVIE_SNAPSHOT_KEEP" flag:"snapshot-keep" usage:"number of snapshots to keep, 0 for no limit"`
+	SnapshotMaxAge time.Duration `yaml:"snapshot_max_age" env:"MOVIE_SNAPSHOT_MAX_AGE" flag:"snapshot-max-age" usage:"drop snapshots older than this, 0 for no limit; the newest is always kept"`
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl" env:"MOVIE_IDEMPOTENCY_TTL" flag:"idempotency-ttl" usage:"how long responses to writes with an idempotency key are replayed, 0 to disable"`
+	IdempotencyMax int           `yaml:"idempotency_max" env:"MOVIE_IDEMPOTENCY_MAX" flag:"idempotency-max" usage:"most responses kept for replay, the oldest are dropped first; 0 for no limit"`
+	WebhookDir     string        `yaml:"webhook_dir" env:"MOVIE_WEBHOOK_DIR" flag:"webhook-dir" usage:"directory of the webhook subscriptions, delivery queue and delivery log"`
+	WebhookTries   int           `yaml:"webhook_tries" env:"MOVIE_WEBHOOK_TRIES" flag:"webhook-tries" usage:"delivery attempts per webhook event before it is dead-lettered"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout" env:"MOVIE_WEBHOOK_TIMEOUT" flag:"webhook-timeout" usage:"how long a webhook receiver has to respond"`
+	FuzzyThreshold float64       `yaml:"fuzzy_threshold" env:"MOVIE_FUZZY_THRESHOLD" flag:"fuzzy-threshold" usage:"default minimum title similarity, 0 to 1, for FuzzyTitleLookup"`
+	TraceExporter  string        `yaml:"trace_exporter" env:"OTEL_TRACES_EXPORTER" flag:"trace-exporter" usage:"span exporter: otlp, stdout or none"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Addr:           ":50051",
+		LibraryPath:    "./herd.json",
+		WatchInterval:  2 * time.Second,
+		PosterDir:      "./posters",
+		MaxPosterBytes: 10 << 20,
+		GenresPath:     "./genres.json",
+		AuditPath:      "./audit.jsonl",
+		SnapshotDir:    "./snapshots",
+		SnapshotKeep:   20,
+		SnapshotMaxAge: 30 * 24 * time.Hour,
+		IdempotencyTTL: 24 * time.Hour,
+		IdempotencyMax: 10000,
+		WebhookDir:     "./webhooks",
+		WebhookTries:   10,
+		WebhookTimeout: 10 * time.Second,
+		FuzzyThreshold: 0.6,
+		TraceExporter:  telemetry.ExporterOTLP,
+	}
+}
+
+// Validate reports the first invalid setting.
+func (c *Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("addr: %w", err)
+	}
+	if c.LibraryPath == "" {
+		return errors.New("library_path must be set")
+	}
+	if c.WatchInterval < 0 {
+		return errors.New("watch_interval must not be negative")
+	}
+	if c.PosterDir == "" {
+		return errors.New("poster_dir must be set")
+	}
+	if c.MaxPosterBytes <= 0 {
+		return errors.New("max_poster_bytes must be positive")
+	}
+	if c.GenresPath == "" {
+		return errors.New("genres_path must be set")
+	}
+	if c.AuditPath == "" {
+		return errors.New("audit_path must be set")
+	}
+	if c.SnapshotDir == "" {
+		return errors.New("snapshot_dir must be set")
+	}
+	if c.SnapshotKeep < 0 {
+		return errors.New("snapshot_keep must not be negative")
+	}
+	if c.SnapshotMaxAge < 0 {
+		return errors.New("snapshot_max_age must not be negative")
+	}
+	if c.IdempotencyTTL < 0 {
+		return errors.New("idempotency_ttl must not be negative")
+	}
+	if c.IdempotencyMax < 0 {
+		return errors.New("idempotency_max must not be negative")
+	}
+	if c.WebhookDir == "" {
+		return errors.New("webhook_dir must be set")
+	}
+	if c.WebhookTries <= 0 {
+		return errors.New("webhook_tries must be positive")
+	}
+	if c.WebhookTimeout <= 0 {
+		return errors.New("webhook_timeout must be positive")
+	}
+	if c.FuzzyThreshold <= 0 || c.FuzzyThreshold > 1 {
+		return errors.New("fuzzy_threshold must be above 0 and at most 1")
+	}
+	switch c.TraceExporter {
+	case telemetry.ExporterOTLP, telemetry.ExporterStdout, telemetry.ExporterNone:
+	default:
+		return fmt.Errorf("trace_exporter: unknown exporter %q", c.TraceExporter)
+	}
+	return nil
+}
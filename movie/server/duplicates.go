@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *movieLibraryServer) FindDuplicates(ctx context.Context, req *pb.FindDuplicatesRequest) (*pb.FindDuplicatesResponse, error) {
+	threshold := req.GetThreshold()
+	switch {
+	case threshold < 0 || threshold > 1:
+		return nil, status.Error(codes.InvalidArgument, "threshold must be between 0 and 1")
+	case threshold == 0:
+		threshold = catalog.DefaultDuplicateThreshold
+	}
+
+	movies := s.lib.all()
+	resp := &pb.FindDuplicatesResponse{}
+	for _, group := range catalog.FindDuplicates(movies, threshold) {
+		g := &pb.DuplicateGroup{}
+		for _, i := range group {
+			g.Movies = append(g.Movies, movies[i].ToProto())
+		}
+		resp.Groups = append(resp.Groups, g)
+	}
+	return resp, nil
+}
+
+func (s *movieLibraryServer) MergeMovies(ctx context.Context, req *pb.MergeMoviesRequest) (*pb.MergeMoviesResponse, error) {
+	if len(req.GetDuplicateIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "duplicate_ids is required")
+	}
+	remove := map[int32]bool{}
+	for _, id := range req.GetDuplicateIds() {
+		if id == req.GetSurvivorId() {
+			return nil, status.Errorf(codes.InvalidArgument, "movie %d cannot be merged into itself", id)
+		}
+		remove[id] = true
+	}
+
+	var merged catalog.Movie
+	err := s.modifyLibrary(ctx, func(movies []catalog.Movie) ([]catalog.Movie, error) {
+		idx := indexOf(movies, req.GetSurvivorId())
+		if idx < 0 {
+			return nil, status.Errorf(codes.NotFound, "movie %d not found", req.GetSurvivorId())
+		}
+		var duplicates []catalog.Movie
+		for _, id := range req.GetDuplicateIds() {
+			d := indexOf(movies, id)
+			if d < 0 {
+				return nil, status.Errorf(codes.NotFound, "movie %d not found", id)
+			}
+			duplicates = append(duplicates, movies[d])
+		}
+
+		merged = catalog.Merge(movies[idx], duplicates...)
+		s.genres.taxonomy().NormalizeGenres(&merged, false)
+		if err := merged.Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "merged movie %d: %v", merged.ID, err)
+		}
+		merged.Version = nextVersion(movies[idx], merged)
+		movies[idx] = merged
+
+		kept := movies[:0]
+		for _, m := range movies {
+			if !remove[m.ID] {
+				kept = append(kept, m)
+			}
+		}
+		return kept, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]int32, 0, len(remove))
+	for _, id := range req.GetDuplicateIds() {
+		if remove[id] {
+			removed = append(removed, id)
+			delete(remove, id)
+		}
+	}
+	return &pb.MergeMoviesResponse{
+		StatusCode: http.StatusOK,
+		Merged:     merged.ToProto(),
+		RemovedIds: removed,
+	}, nil
+}
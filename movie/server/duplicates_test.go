@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMergeMovies(t *testing.T) {
+	s := newTestServer(t)
+	dup := sholay
+	dup.Title, dup.RuntimeMinutes = "SHOLAY", 204
+	load(t, s, sholay, deewar, dup)
+
+	found, err := s.FindDuplicates(context.Background(), &pb.FindDuplicatesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Groups) != 1 || len(found.Groups[0].Movies) != 2 || found.Groups[0].Movies[1].Id != 3 {
+		t.Fatalf("duplicate groups %v, want movies 1 and 3", found.Groups)
+	}
+
+	resp, err := s.MergeMovies(context.Background(), &pb.MergeMoviesRequest{SurvivorId: 1, DuplicateIds: []int32{3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := resp.Merged; m.Id != 1 || m.Title != "Sholay" || m.RuntimeMinutes != 204 || m.Version != 2 {
+		t.Errorf("merged %v, want Sholay at version 2 with the runtime of its duplicate", m)
+	}
+	if !reflect.DeepEqual(resp.RemovedIds, []int32{3}) {
+		t.Errorf("removed %v, want [3]", resp.RemovedIds)
+	}
+	if got, want := titles(s), map[int32]string{1: "Sholay", 2: "Deewar"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("library = %v, want %v", got, want)
+	}
+}
+
+func TestMergeMoviesErrors(t *testing.T) {
+	s := newTestServer(t)
+	load(t, s, sholay, deewar)
+
+	tests := []struct {
+		req  *pb.MergeMoviesRequest
+		code codes.Code
+	}{
+		{&pb.MergeMoviesRequest{SurvivorId: 1}, codes.InvalidArgument},
+		{&pb.MergeMoviesRequest{SurvivorId: 1, DuplicateIds: []int32{1}}, codes.InvalidArgument},
+		{&pb.MergeMoviesRequest{SurvivorId: 9, DuplicateIds: []int32{2}}, codes.NotFound},
+		{&pb.MergeMoviesRequest{SurvivorId: 1, DuplicateIds: []int32{9}}, codes.NotFound},
+	}
+	for _, tt := range tests {
+		if _, err := s.MergeMovies(context.Background(), tt.req); status.Code(err) != tt.code {
+			t.Errorf("MergeMovies(%v) = %v, want %v", tt.req, err, tt.code)
+		}
+	}
+}
+
+func TestMergeMoviesInvalid(t *testing.T) {
+	s := newTestServer(t)
+	// A library file edited by hand may hold invalid movies; merging one
+	// must not save it.
+	bad := sholay
+	bad.ID, bad.ReleaseDate = 1, "1975-08-15"
+	dup := sholay
+	dup.ID = 2
+	data, err := json.Marshal([]catalog.Movie{bad, dup})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(s.libraryPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.loadLibrary(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.MergeMovies(context.Background(), &pb.MergeMoviesRequest{SurvivorId: 1, DuplicateIds: []int32{2}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("MergeMovies = %v, want InvalidArgument", err)
+	}
+	if got := len(s.lib.all()); got != 2 {
+		t.Errorf("library has %d movies after a failed merge, want 2", got)
+	}
+	after, err := os.ReadFile(s.libraryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(data) {
+		t.Errorf("library file rewritten to %s", after)
+	}
+}
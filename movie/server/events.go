@@ -0,0 +1,202 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Event types sent by WatchMovies.
+const (
+	eventCreated  = "created"
+	eventUpdated  = "updated"
+	eventDeleted  = "deleted"
+	eventReloaded = "reloaded" // the library file was replaced outside the server
+)
+
+// eventHistory is how many past events are kept for subscribers that
+// resume after a disconnect or are still sending earlier ones. A single
+// publish larger than that, such as a big LoadMovies, is kept whole.
+const eventHistory = 1024
+
+// movieEvent is a change to the library. Movie is the movie after the
+// change, or before it for deletions; reloaded events have none.
+type movieEvent struct {
+	Seq     uint64
+	Type    string
+	MovieID int32
+	Movie   *catalog.Movie
+	Time    time.Time
+	Action  string
+	Actor   string
+}
+
+// eventBus hands library changes to WatchMovies subscribers. Events are
+// numbered from 1 in the order they were published; numbering restarts with
+// the server. Subscribers read the events from the history at their own
+// pace; publish only wakes them.
+type eventBus struct {
+	mu      sync.Mutex
+	seq     uint64
+	history []movieEvent // at least the latest eventHistory events, oldest first
+	subs    map[*subscriber]bool
+}
+
+// subscriber is woken through notify whenever events are published.
+type subscriber struct {
+	notify chan struct{}
+}
+
+// eventsFromAudit turns stamped audit entries into unnumbered events.
+func eventsFromAudit(entries []auditEntry) []movieEvent {
+	events := make([]movieEvent, 0, len(entries))
+	for _, e := range entries {
+		ev := movieEvent{MovieID: e.MovieID, Time: e.Time, Action: e.Action, Actor: e.Actor}
+		switch e.Op {
+		case opCreate:
+			ev.Type, ev.Movie = eventCreated, e.After
+		case opUpdate:
+			ev.Type, ev.Movie = eventUpdated, e.After
+		case opDelete:
+			ev.Type, ev.Movie = eventDeleted, e.Before
+		default:
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+// publish numbers events, wakes every subscriber and returns the events.
+func (b *eventBus) publish(events []movieEvent) []movieEvent {
+	if len(events) == 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range events {
+		b.seq++
+		events[i].Seq = b.seq
+	}
+	b.history = append(b.history, events...)
+	keep := eventHistory
+	if len(events) > keep {
+		keep = len(events)
+	}
+	if n := len(b.history) - keep; n > 0 {
+		b.history = append(b.history[:0:0], b.history[n:]...)
+	}
+	for sub := range b.subs {
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+	return events
+}
+
+// subscribe registers a subscriber for the events after seq after and
+// returns the seq to read from with since; after 0 subscribes to new events
+// only. Resuming is impossible, and reported as codes.OutOfRange, when seq
+// was never published (the server restarted) or events after it have been
+// dropped from the history.
+func (b *eventBus) subscribe(after uint64) (*subscriber, uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if after == 0 {
+		after = b.seq
+	}
+	if after > b.seq {
+		return nil, 0, status.Errorf(codes.OutOfRange, "event %d has not been published; the latest is %d", after, b.seq)
+	}
+	if _, err := b.sinceLocked(after); err != nil {
+		return nil, 0, err
+	}
+	sub := &subscriber{notify: make(chan struct{}, 1)}
+	if b.subs == nil {
+		b.subs = map[*subscriber]bool{}
+	}
+	b.subs[sub] = true
+	return sub, after, nil
+}
+
+// since returns the events published after seq after, oldest first, or
+// codes.OutOfRange when some of them are no longer in the history. The
+// slice must not be modified.
+func (b *eventBus) since(after uint64) ([]movieEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sinceLocked(after)
+}
+
+func (b *eventBus) sinceLocked(after uint64) ([]movieEvent, error) {
+	first := b.seq - uint64(len(b.history)) + 1
+	if after+1 < first {
+		return nil, status.Errorf(codes.OutOfRange, "events before %d are no longer available", first)
+	}
+	// Elements of history are never changed, only dropped, so the events
+	// can be read after the lock is released.
+	return b.history[after+1-first : len(b.history) : len(b.history)], nil
+}
+
+func (b *eventBus) unsubscribe(sub *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, sub)
+}
+
+func eventToProto(ev movieEvent) *pb.MovieEvent {
+	p := &pb.MovieEvent{
+		Seq:      ev.Seq,
+		Type:     ev.Type,
+		MovieId:  ev.MovieID,
+		TimeUnix: ev.Time.Unix(),
+		Action:   ev.Action,
+		Actor:    ev.Actor,
+	}
+	if ev.Movie != nil {
+		p.Movie = ev.Movie.ToProto()
+	}
+	return p
+}
+
+// WatchMovies streams library changes as they happen, starting after
+// req.AfterSeq when resuming. A subscriber that falls so far behind that
+// the events it has yet to send leave the history gets codes.OutOfRange,
+// like a resume that is too late.
+func (s *movieLibraryServer) WatchMovies(req *pb.WatchMoviesRequest, stream pb.MovieLibraryService_WatchMoviesServer) error {
+	sub, last, err := s.events.subscribe(req.GetAfterSeq())
+	if err != nil {
+		return err
+	}
+	defer s.events.unsubscribe(sub)
+	// Send the headers now, so clients know the subscription succeeded
+	// before the first event arrives.
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		events, err := s.events.since(last)
+		if err != nil {
+			return err
+		}
+		for _, ev := range events {
+			if err := stream.Send(eventToProto(ev)); err != nil {
+				return err
+			}
+			last = ev.Seq
+		}
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-sub.notify:
+		}
+	}
+}
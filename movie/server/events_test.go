@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "movie/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// publishN publishes n events on b.
+func publishN(b *eventBus, n int) {
+	events := make([]movieEvent, n)
+	for i := range events {
+		events[i] = movieEvent{Type: eventUpdated, MovieID: 1}
+	}
+	b.publish(events)
+}
+
+func seqs(events []movieEvent) []uint64 {
+	var s []uint64
+	for _, ev := range events {
+		s = append(s, ev.Seq)
+	}
+	return s
+}
+
+func TestEventResume(t *testing.T) {
+	b := &eventBus{}
+	publishN(b, 5)
+
+	tests := []struct {
+		after     uint64
+		wantFirst uint64 // seq subscribe returns
+		wantSince []uint64
+		code      codes.Code
+	}{
+		{after: 0, wantFirst: 5},
+		{after: 3, wantFirst: 3, wantSince: []uint64{4, 5}},
+		{after: 5, wantFirst: 5},
+		{after: 6, code: codes.OutOfRange},
+	}
+	for _, tt := range tests {
+		sub, first, err := b.subscribe(tt.after)
+		if status.Code(err) != tt.code {
+			t.Errorf("subscribe(%d) = %v, want %v", tt.after, err, tt.code)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		events, err := b.since(first)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first != tt.wantFirst || !reflect.DeepEqual(seqs(events), tt.wantSince) {
+			t.Errorf("subscribe(%d) resumed after %d with %v, want after %d with %v", tt.after, first, seqs(events), tt.wantFirst, tt.wantSince)
+		}
+		b.unsubscribe(sub)
+	}
+}
+
+func TestEventHistory(t *testing.T) {
+	b := &eventBus{}
+	publishN(b, 10)
+	sub, last, err := b.subscribe(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.unsubscribe(sub)
+
+	// The subscriber is woken, and the history drops the events it has
+	// yet to read.
+	publishN(b, eventHistory)
+	select {
+	case <-sub.notify:
+	default:
+		t.Error("subscriber not woken")
+	}
+	if _, err := b.since(last); status.Code(err) != codes.OutOfRange {
+		t.Errorf("since(%d) = %v, want OutOfRange", last, err)
+	}
+	// Events 11 on are kept: resuming after 10 works, after 9 does not.
+	if _, _, err := b.subscribe(9); status.Code(err) != codes.OutOfRange {
+		t.Errorf("subscribe(9) = %v, want OutOfRange", err)
+	}
+	if events, err := b.since(10); err != nil || len(events) != eventHistory || events[0].Seq != 11 {
+		t.Errorf("since(10) = %d events, %v, want %d from 11", len(events), err, eventHistory)
+	}
+
+	// A publish larger than the history is kept whole.
+	publishN(b, eventHistory+10)
+	if events, err := b.since(10 + eventHistory); err != nil || len(events) != eventHistory+10 {
+		t.Errorf("since(%d) = %d events, %v, want %d", 10+eventHistory, len(events), err, eventHistory+10)
+	}
+}
+
+// watchStream collects the events sent by WatchMovies.
+type watchStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *pb.MovieEvent
+}
+
+func (s *watchStream) Context() context.Context     { return s.ctx }
+func (s *watchStream) SendHeader(metadata.MD) error { return nil }
+func (s *watchStream) Send(ev *pb.MovieEvent) error { s.sent <- ev; return nil }
+func (s *watchStream) recv(t *testing.T) *pb.MovieEvent {
+	t.Helper()
+	select {
+	case ev := <-s.sent:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event sent")
+		return nil
+	}
+}
+
+func TestWatchMoviesResume(t *testing.T) {
+	s := newTestServer(t)
+	load(t, s, sholay, deewar)
+	load(t, s, sholay, deewar, lagaan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &watchStream{ctx: ctx, sent: make(chan *pb.MovieEvent, 16)}
+	done := make(chan error, 1)
+	go func() { done <- s.WatchMovies(&pb.WatchMoviesRequest{AfterSeq: 1}, stream) }()
+
+	// Events 2 and 3 were published before the call, 4 after it.
+	for _, want := range []struct {
+		seq   uint64
+		title string
+	}{{2, "Deewar"}, {3, "Lagaan"}} {
+		if ev := stream.recv(t); ev.Seq != want.seq || ev.Movie.GetTitle() != want.title || ev.Type != eventCreated {
+			t.Errorf("event %v, want %d creating %s", ev, want.seq, want.title)
+		}
+	}
+	load(t, s, sholay, lagaan)
+	if ev := stream.recv(t); ev.Seq != 4 || ev.Type != eventDeleted || ev.MovieId != 2 {
+		t.Errorf("event %v, want 4 deleting movie 2", ev)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("WatchMovies = %v", err)
+	}
+
+	err := s.WatchMovies(&pb.WatchMoviesRequest{AfterSeq: 9}, &watchStream{ctx: context.Background()})
+	if status.Code(err) != codes.OutOfRange {
+		t.Errorf("WatchMovies after an unpublished seq = %v, want OutOfRange", err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// genreStore holds the genre taxonomy and the JSON file it is saved to.
+type genreStore struct {
+	mu   sync.RWMutex
+	tax  *catalog.Taxonomy
+	path string
+}
+
+// loadGenres reads the taxonomy from path. Without a file the server uses
+// catalog.DefaultGenres until the first UpsertGenre call saves one.
+func loadGenres(path string) (*genreStore, error) {
+	genres := catalog.DefaultGenres
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		genres = nil
+		if err := json.Unmarshal(data, &genres); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	tax, err := catalog.NewTaxonomy(genres)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &genreStore{tax: tax, path: path}, nil
+}
+
+func (g *genreStore) taxonomy() *catalog.Taxonomy {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.tax
+}
+
+// upsert adds or replaces genre and saves the taxonomy. It reports whether
+// the genre is new.
+func (g *genreStore) upsert(genre catalog.Genre) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, exists := g.tax.Genre(genre.ID)
+	tax, err := g.tax.Upsert(genre)
+	if err != nil {
+		return false, status.Error(codes.InvalidArgument, err.Error())
+	}
+	data, err := json.MarshalIndent(tax.Genres(), "", "  ")
+	if err != nil {
+		return false, status.Errorf(codes.Internal, "encode genres: %v", err)
+	}
+	if err := writeFileAtomic(g.path, data, 0644); err != nil {
+		return false, status.Errorf(codes.Internal, "save genres: %v", err)
+	}
+	g.tax = tax
+	return !exists, nil
+}
+
+// normalizeMovie trims m and maps its genres to taxonomy IDs. With
+// strictGenres set, unknown genres are reported as codes.InvalidArgument.
+func (s *movieLibraryServer) normalizeMovie(m *catalog.Movie) error {
+	if err := s.genres.taxonomy().NormalizeGenres(m, s.strictGenres); err != nil {
+		return status.Errorf(codes.InvalidArgument, "movie %q: %v", m.Title, err)
+	}
+	return nil
+}
+
+// normalizeLibrary normalizes movies read from the library file like
+// normalizeMovie, but keeps unknown genres: the file may predate the
+// taxonomy or have been edited by hand.
+func (s *movieLibraryServer) normalizeLibrary(movies []catalog.Movie) {
+	t := s.genres.taxonomy()
+	for i := range movies {
+		t.NormalizeGenres(&movies[i], false)
+	}
+}
+
+func (s *movieLibraryServer) ListGenres(ctx context.Context, req *pb.ListGenresRequest) (*pb.ListGenresResponse, error) {
+	var genres []*pb.Genre
+	for _, g := range s.genres.taxonomy().Genres() {
+		genres = append(genres, g.ToProto(req.GetLanguage()))
+	}
+	return &pb.ListGenresResponse{Genres: genres}, nil
+}
+
+func (s *movieLibraryServer) UpsertGenre(ctx context.Context, req *pb.UpsertGenreRequest) (*pb.UpsertGenreResponse, error) {
+	if req.GetGenre() == nil {
+		return nil, status.Error(codes.InvalidArgument, "genre is required")
+	}
+	genre := catalog.GenreFromProto(req.GetGenre())
+	sort.Strings(genre.Aliases)
+
+	created, err := s.genres.upsert(genre)
+	if err != nil {
+		return nil, err
+	}
+	code := http.StatusOK
+	if created {
+		code = http.StatusCreated
+	}
+	return &pb.UpsertGenreResponse{
+		StatusCode: int32(code),
+		Genre:      genre.ToProto(""),
+	}, nil
+}
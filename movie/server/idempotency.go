@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Metadata keys of idempotent requests. The gateway passes the
+// Idempotency-Key header on as idempotencyKey; replayed responses carry
+// idempotentReplayKey in their header.
+const (
+	idempotencyKey      = "idempotency-key"
+	idempotentReplayKey = "idempotent-replay"
+)
+
+const maxIdempotencyKeyLen = 255
+
+// idempotentMethods are the unary RPCs that change the library and may
+// therefore be retried with an idempotency key.
+var idempotentMethods = map[string]bool{
+	"LoadMovies":         true,
+	"UpdateMovieDetails": true,
+	"UpsertGenre":        true,
+	"MergeMovies":        true,
+	"RestoreSnapshot":    true,
+	"RegisterWebhook":    true,
+	"DeleteWebhook":      true,
+	"BatchUpdateMovies":  true,
+}
+
+// idempotentCall is the outcome of the first call with an idempotency key.
+// done is closed once the call has finished; calls that failed are
+// forgotten so they can be retried.
+type idempotentCall struct {
+	id      string
+	done    chan struct{}
+	sum     [sha256.Size]byte // of the request
+	resp    proto.Message
+	err     error
+	expires time.Time
+}
+
+// idempotencyCache remembers the responses of writes made with an
+// idempotency key for ttl, and returns them again when a client retries
+// the same request with the same key. A zero ttl disables it. At most max
+// responses are kept, dropping the oldest first; zero means no limit.
+type idempotencyCache struct {
+	ttl time.Duration
+	max int
+
+	mu       sync.Mutex
+	calls    map[string]*idempotentCall // by method and key
+	finished []*idempotentCall          // successful calls, oldest first
+}
+
+func newIdempotencyCache(ttl time.Duration, max int) *idempotencyCache {
+	return &idempotencyCache{ttl: ttl, max: max, calls: map[string]*idempotentCall{}}
+}
+
+// unaryInterceptor runs handler once per method and idempotency key. A
+// retry while the first call is still running waits for its result; using
+// a key again with a different request is rejected.
+func (c *idempotencyCache) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	method := path.Base(info.FullMethod)
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(idempotencyKey)
+	if c.ttl <= 0 || !idempotentMethods[method] || len(keys) == 0 || keys[0] == "" {
+		return handler(ctx, req)
+	}
+	key := keys[0]
+	if len(key) > maxIdempotencyKeyLen {
+		return nil, status.Errorf(codes.InvalidArgument, "idempotency key longer than %d bytes", maxIdempotencyKeyLen)
+	}
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return handler(ctx, req)
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "encode request: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	id := method + "\x00" + key
+
+	for {
+		c.mu.Lock()
+		c.expire(time.Now())
+		call, found := c.calls[id]
+		if !found {
+			call = &idempotentCall{id: id, done: make(chan struct{}), sum: sum}
+			c.calls[id] = call
+			c.mu.Unlock()
+			return c.run(ctx, req, handler, id, call)
+		}
+		c.mu.Unlock()
+
+		if call.sum != sum {
+			return nil, status.Errorf(codes.InvalidArgument, "idempotency key %q was already used for a different %s request", key, method)
+		}
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		if call.err != nil {
+			continue // the first call failed; try again ourselves
+		}
+		grpc.SetHeader(ctx, metadata.Pairs(idempotentReplayKey, "true"))
+		return proto.Clone(call.resp), nil
+	}
+}
+
+func (c *idempotencyCache) run(ctx context.Context, req interface{}, handler grpc.UnaryHandler, id string, call *idempotentCall) (interface{}, error) {
+	resp, err := handler(ctx, req)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m, ok := resp.(proto.Message); ok && err == nil {
+		call.resp = m
+		call.expires = time.Now().Add(c.ttl)
+		c.finished = append(c.finished, call)
+		c.expire(time.Now())
+	} else {
+		call.err = err
+		if call.err == nil {
+			call.err = status.Error(codes.Internal, "no response")
+		}
+		delete(c.calls, id)
+	}
+	close(call.done)
+	return resp, err
+}
+
+// expire drops the calls whose ttl has passed, and the oldest ones beyond
+// max. Calls still running are never dropped. c.mu must be held.
+func (c *idempotencyCache) expire(now time.Time) {
+	n := 0
+	for n < len(c.finished) && (now.After(c.finished[n].expires) || (c.max > 0 && len(c.finished)-n > c.max)) {
+		call := c.finished[n]
+		if c.calls[call.id] == call {
+			delete(c.calls, call.id)
+		}
+		c.finished[n] = nil
+		n++
+	}
+	c.finished = c.finished[n:]
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "movie/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var updateInfo = &grpc.UnaryServerInfo{FullMethod: "/movie.MovieLibraryService/UpdateMovieDetails"}
+
+// countingHandler answers every call with a response numbered after the
+// calls made so far.
+type countingHandler struct {
+	calls int32
+	err   error // returned by the first call, when set
+}
+
+func (h *countingHandler) handle(ctx context.Context, req interface{}) (interface{}, error) {
+	n := atomic.AddInt32(&h.calls, 1)
+	if n == 1 && h.err != nil {
+		return nil, h.err
+	}
+	return &pb.UpdateMovieDetailsResponse{StatusCode: 200 + n}, nil
+}
+
+// call makes an UpdateMovieDetails call for movie id with key.
+func call(c *idempotencyCache, h grpc.UnaryHandler, key string, id int32) (int32, error) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotencyKey, key))
+	resp, err := c.unaryInterceptor(ctx, &pb.UpdateMovieDetailsRequest{MovieId: id}, updateInfo, h)
+	if err != nil {
+		return 0, err
+	}
+	return resp.(*pb.UpdateMovieDetailsResponse).StatusCode, nil
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	c := newIdempotencyCache(time.Hour, 0)
+	h := &countingHandler{}
+	for i := 0; i < 3; i++ {
+		if got, err := call(c, h.handle, "k1", 1); err != nil || got != 201 {
+			t.Errorf("call %d = %d, %v, want the first response", i+1, got, err)
+		}
+	}
+	if got, _ := call(c, h.handle, "k2", 1); got != 202 {
+		t.Errorf("call with another key = %d, want a new response", got)
+	}
+	if h.calls != 2 {
+		t.Errorf("handler ran %d times, want 2", h.calls)
+	}
+}
+
+func TestIdempotencyKeyReused(t *testing.T) {
+	c := newIdempotencyCache(time.Hour, 0)
+	h := &countingHandler{}
+	if _, err := call(c, h.handle, "k", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := call(c, h.handle, "k", 2); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("reused key = %v, want InvalidArgument", err)
+	}
+	if h.calls != 1 {
+		t.Errorf("handler ran %d times, want 1", h.calls)
+	}
+}
+
+func TestIdempotentWaiter(t *testing.T) {
+	c := newIdempotencyCache(time.Hour, 0)
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls int32
+	h := func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		<-release
+		return &pb.UpdateMovieDetailsResponse{StatusCode: 201}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int32, 2)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], _ = call(c, h, "k", 1)
+	}()
+	<-started
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[1], _ = call(c, h, "k", 1)
+	}()
+	time.Sleep(20 * time.Millisecond) // let the retry find the running call
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+	if results[0] != 201 || results[1] != 201 {
+		t.Errorf("responses %v, want 201 twice", results)
+	}
+}
+
+func TestIdempotentFailureForgotten(t *testing.T) {
+	c := newIdempotencyCache(time.Hour, 0)
+	h := &countingHandler{err: status.Error(codes.Unavailable, "library busy")}
+	if _, err := call(c, h.handle, "k", 1); status.Code(err) != codes.Unavailable {
+		t.Fatalf("first call = %v, want Unavailable", err)
+	}
+	if got, err := call(c, h.handle, "k", 1); err != nil || got != 202 {
+		t.Errorf("retry = %d, %v, want a new response", got, err)
+	}
+	if got, _ := call(c, h.handle, "k", 1); got != 202 {
+		t.Errorf("second retry = %d, want the replayed 202", got)
+	}
+}
+
+func TestIdempotencyEviction(t *testing.T) {
+	c := newIdempotencyCache(time.Hour, 2)
+	h := &countingHandler{}
+	for _, key := range []string{"a", "b", "c"} {
+		call(c, h.handle, key, 1)
+	}
+	// "a" is the oldest of three and has been dropped; "c" is kept.
+	if got, _ := call(c, h.handle, "c", 1); got != 203 {
+		t.Errorf("replay of c = %d, want 203", got)
+	}
+	if got, _ := call(c, h.handle, "a", 1); got != 204 {
+		t.Errorf("call with dropped key a = %d, want a new response", got)
+	}
+	if n := len(c.calls); n != 2 {
+		t.Errorf("%d responses kept, want 2", n)
+	}
+
+	c = newIdempotencyCache(10*time.Millisecond, 0)
+	h = &countingHandler{}
+	call(c, h.handle, "a", 1)
+	time.Sleep(20 * time.Millisecond)
+	if got, _ := call(c, h.handle, "a", 1); got != 202 {
+		t.Errorf("call after the ttl = %d, want a new response", got)
+	}
+}
+
+func TestIdempotencyDisabled(t *testing.T) {
+	c := newIdempotencyCache(0, 0)
+	h := &countingHandler{}
+	call(c, h.handle, "k", 1)
+	call(c, h.handle, "k", 1)
+	if h.calls != 2 {
+		t.Errorf("handler ran %d times, want 2", h.calls)
+	}
+}
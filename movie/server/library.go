@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"movie/catalog"
+	"movie/search"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recentSums is how many checksums of its own library files the server
+// remembers, so that the watcher never mistakes one of them for an outside
+// edit.
+const recentSums = 16
+
+// library is the in-memory copy of the JSON library file. Readers always see
+// a complete slice: updates build a new slice and swap it in under the lock.
+type library struct {
+	mu     sync.RWMutex
+	movies []catalog.Movie
+	sums   [][sha256.Size]byte // checksums of the latest files movies came from, oldest first
+	index  *search.Index       // full-text index of movies
+
+	// nextID is the lowest ID never handed out. It only grows, so the ID
+	// of a removed movie is not given to another one. savedNextID is the
+	// value in the IDs file.
+	nextID, savedNextID int32
+}
+
+// set replaces the movies and rebuilds the search index. l.mu must be held.
+func (l *library) set(movies []catalog.Movie, sum [sha256.Size]byte) {
+	docs := make([]search.Document, len(movies))
+	for i, m := range movies {
+		docs[i] = search.Document{ID: m.ID, Title: m.Title, Synopsis: m.Synopsis}
+	}
+	l.movies, l.index = movies, search.NewIndex(docs)
+	l.nextID = nextFreeID(l.nextID, movies)
+	l.sums = append(l.sums, sum)
+	if n := len(l.sums) - recentSums; n > 0 {
+		l.sums = append(l.sums[:0:0], l.sums[n:]...)
+	}
+}
+
+// known reports whether sum is the checksum of one of the latest library
+// files. l.mu must be held.
+func (l *library) known(sum [sha256.Size]byte) bool {
+	for _, s := range l.sums {
+		if s == sum {
+			return true
+		}
+	}
+	return false
+}
+
+// all returns the current movies. The slice must not be modified.
+func (l *library) all() []catalog.Movie {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.movies
+}
+
+// current returns the movies together with the next ID to hand out. The
+// slice must not be modified.
+func (l *library) current() ([]catalog.Movie, int32) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.movies, l.nextID
+}
+
+// searchIndex returns the movies together with their search index, which
+// is nil until a library has been loaded.
+func (l *library) searchIndex() ([]catalog.Movie, *search.Index) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.movies, l.index
+}
+
+// nextFreeID returns next, or the ID after the highest of movies when that
+// is larger.
+func nextFreeID(next int32, movies []catalog.Movie) int32 {
+	if next < 1 {
+		next = 1
+	}
+	for _, m := range movies {
+		if m.ID >= next {
+			next = m.ID + 1
+		}
+	}
+	return next
+}
+
+// assignIDs gives every movie without an ID the next free one from next
+// on, in order. Files written before IDs existed thus get their 1-based
+// positions.
+func assignIDs(movies []catalog.Movie, next int32) {
+	next = nextFreeID(next, movies)
+	for i := range movies {
+		if movies[i].ID == 0 {
+			movies[i].ID = next
+			next++
+		}
+	}
+}
+
+// keepIDs returns the movies of a load as they replace current. A movie
+// matching one of current, as catalog.MatchMovies pairs them, keeps its ID
+// and poster; the others get new IDs from next on, so an ID never moves to
+// a different film.
+func keepIDs(current, loaded []catalog.Movie, next int32) []catalog.Movie {
+	next = nextFreeID(next, current)
+	movies := make([]catalog.Movie, len(loaded))
+	copy(movies, loaded)
+	for i, j := range catalog.MatchMovies(current, movies) {
+		movies[i].Version = 0
+		if j >= 0 {
+			movies[i].ID, movies[i].PosterURL = current[j].ID, current[j].PosterURL
+			continue
+		}
+		movies[i].ID, movies[i].PosterURL = next, ""
+		next++
+	}
+	return movies
+}
+
+// loadedLibrary returns the library a load of movies makes of current: the
+// movies with the IDs keepIDs gives them. It fails with
+// codes.InvalidArgument when they do not pass validateLibrary. LoadMovies
+// and its dry run both use it, so a preview shows exactly what a load does.
+func loadedLibrary(current, movies []catalog.Movie, next int32) ([]catalog.Movie, error) {
+	movies = keepIDs(current, movies, next)
+	if err := validateLibrary(movies); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return movies, nil
+}
+
+// setVersions carries the versions of before over to after, incrementing
+// those of movies that changed. Movies new to the library start at their
+// own version, or 1.
+func setVersions(before, after []catalog.Movie) {
+	old := make(map[int32]catalog.Movie, len(before))
+	for _, m := range before {
+		old[m.ID] = m
+	}
+	for i := range after {
+		if o, ok := old[after[i].ID]; ok {
+			after[i].Version = nextVersion(o, after[i])
+		} else if after[i].Version <= 0 {
+			after[i].Version = 1
+		}
+	}
+}
+
+// nextVersion returns the version of m, which replaces o.
+func nextVersion(o, m catalog.Movie) int64 {
+	if len(movieChanges(o, m)) > 0 {
+		return o.Version + 1
+	}
+	return o.Version
+}
+
+// indexOf returns the position of the movie with id, or -1.
+func indexOf(movies []catalog.Movie, id int32) int {
+	for i, m := range movies {
+		if m.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// validateLibrary reports the first movie with a duplicate ID or that fails
+// catalog.Movie.Validate.
+func validateLibrary(movies []catalog.Movie) error {
+	seen := map[int32]bool{}
+	for i, m := range movies {
+		if m.ID != 0 {
+			if seen[m.ID] {
+				return fmt.Errorf("movie %d (%s): duplicate id %d", i+1, m.Title, m.ID)
+			}
+			seen[m.ID] = true
+		}
+		if err := m.Validate(); err != nil {
+			if m.Title == "" {
+				return fmt.Errorf("movie %d: %v", i+1, err)
+			}
+			return fmt.Errorf("movie %d (%s): %v", i+1, m.Title, err)
+		}
+	}
+	return nil
+}
+
+// loadLibrary reads the library file into memory at startup and normalizes
+// its movies. A missing file leaves the library empty; entries that fail
+// validation are only reported, since they may have been stored through the
+// API.
+func (s *movieLibraryServer) loadLibrary(ctx context.Context) error {
+	next, err := s.readNextID()
+	if err != nil {
+		return err
+	}
+	s.lib.mu.Lock()
+	s.lib.nextID, s.lib.savedNextID = next, next
+	s.lib.mu.Unlock()
+
+	data, err := s.readLibraryFile(ctx)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var movies []catalog.Movie
+	if err := json.Unmarshal(data, &movies); err != nil {
+		return err
+	}
+	s.normalizeLibrary(movies)
+	if err := validateLibrary(movies); err != nil {
+		log.Printf("Library %s has invalid entries: %v", s.libraryPath, err)
+	}
+	assignIDs(movies, next)
+	setVersions(nil, movies)
+
+	s.lib.mu.Lock()
+	s.lib.set(movies, sha256.Sum256(data))
+	s.lib.mu.Unlock()
+	return nil
+}
+
+// saveLibrary replaces the library with the movies replace returns for the
+// current ones. The library it replaces is kept as a snapshot first; the
+// returned snapshot is nil when the library was empty. Errors from replace
+// are returned unchanged.
+func (s *movieLibraryServer) saveLibrary(ctx context.Context, replace func(current []catalog.Movie) ([]catalog.Movie, error)) (*snapshot, error) {
+	var backup *snapshot
+	err := s.modifyLibrary(ctx, func(current []catalog.Movie) ([]catalog.Movie, error) {
+		movies, err := replace(current)
+		if err != nil {
+			return nil, err
+		}
+		if len(current) == 0 {
+			return movies, nil
+		}
+		snap, err := s.snapshots.save(ctx, current)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "save snapshot: %v", err)
+		}
+		backup = &snap
+		return movies, nil
+	})
+	return backup, err
+}
+
+// modifyLibrary calls fn with a copy of the current movies and saves the
+// slice it returns. The library stays locked throughout, so concurrent
+// modifications cannot overwrite each other. Movies that changed get the
+// next version. Once the library is saved, every change is written to the
+// audit log and published to WatchMovies subscribers and webhooks. Errors
+// from fn are returned unchanged; a failed save is reported as
+// codes.Internal. A failed audit write is only logged, since the change has
+// been made by then.
+func (s *movieLibraryServer) modifyLibrary(ctx context.Context, fn func([]catalog.Movie) ([]catalog.Movie, error)) error {
+	s.lib.mu.Lock()
+	defer s.lib.mu.Unlock()
+
+	movies := make([]catalog.Movie, len(s.lib.movies))
+	copy(movies, s.lib.movies)
+	movies, err := fn(movies)
+	if err != nil {
+		return err
+	}
+	setVersions(s.lib.movies, movies)
+
+	entries := diffLibraries(s.lib.movies, movies)
+	action, actor, requestID := requestInfo(ctx)
+	stamp(entries, action, actor, requestID)
+
+	data, err := json.Marshal(movies)
+	if err != nil {
+		return status.Errorf(codes.Internal, "encode library: %v", err)
+	}
+	if err := s.saveNextID(nextFreeID(s.lib.nextID, movies)); err != nil {
+		return status.Errorf(codes.Internal, "save next id: %v", err)
+	}
+	if err := s.writeLibraryFile(ctx, data); err != nil {
+		return status.Errorf(codes.Internal, "save library: %v", err)
+	}
+	s.lib.set(movies, sha256.Sum256(data))
+	if err := s.audit.append(ctx, entries); err != nil {
+		log.Printf("Library change of request %s not audited: %v", requestID, err)
+	}
+	s.notify(eventsFromAudit(entries))
+	return nil
+}
+
+// notify publishes events to WatchMovies subscribers and queues them for
+// the webhooks. s.lib.mu must be held, so events are queued in order.
+func (s *movieLibraryServer) notify(events []movieEvent) {
+	events = s.events.publish(events)
+	if s.webhooks != nil {
+		s.webhooks.enqueue(events)
+	}
+}
+
+// watchLibrary polls the library file every interval and swaps in its
+// contents when it was changed by someone other than this server. Files that
+// fail validation are rejected and the current library is kept.
+func (s *movieLibraryServer) watchLibrary(ctx context.Context, interval time.Duration) {
+	var lastMod time.Time
+	var lastSize int64 = -1
+	if fi, err := os.Stat(s.libraryPath); err == nil {
+		lastMod, lastSize = fi.ModTime(), fi.Size()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fi, err := os.Stat(s.libraryPath)
+		if err != nil {
+			continue
+		}
+		if fi.ModTime().Equal(lastMod) && fi.Size() == lastSize {
+			continue
+		}
+		lastMod, lastSize = fi.ModTime(), fi.Size()
+		s.reloadLibrary()
+	}
+}
+
+// reloadLibrary swaps in the library file when it holds an outside edit,
+// snapshotting the library it replaces first. The file is read under the
+// library lock, so it cannot be an older write of this server that a
+// concurrent modifyLibrary has since replaced.
+func (s *movieLibraryServer) reloadLibrary() {
+	s.lib.mu.Lock()
+	defer s.lib.mu.Unlock()
+
+	data, err := os.ReadFile(s.libraryPath)
+	if err != nil {
+		log.Printf("Library reload rejected: %v", err)
+		return
+	}
+	sum := sha256.Sum256(data)
+	if s.lib.known(sum) {
+		// One of our own writes, or a touch without changes.
+		return
+	}
+	var movies []catalog.Movie
+	if err := json.Unmarshal(data, &movies); err != nil {
+		log.Printf("Library reload of %s rejected: %v", s.libraryPath, err)
+		return
+	}
+	s.normalizeLibrary(movies)
+	if err := validateLibrary(movies); err != nil {
+		log.Printf("Library reload of %s rejected: %v", s.libraryPath, err)
+		return
+	}
+	if len(s.lib.movies) > 0 {
+		if _, err := s.snapshots.saveSnapshot(context.Background(), snapshot{Action: "reload", Movies: s.lib.movies}); err != nil {
+			log.Printf("Library reload of %s rejected: save snapshot: %v", s.libraryPath, err)
+			return
+		}
+	}
+	assignIDs(movies, s.lib.nextID)
+	if err := s.saveNextID(nextFreeID(s.lib.nextID, movies)); err != nil {
+		log.Printf("Library reload of %s rejected: save next id: %v", s.libraryPath, err)
+		return
+	}
+	setVersions(s.lib.movies, movies)
+	entries := diffLibraries(s.lib.movies, movies)
+	stamp(entries, "reload", "", "")
+	if err := s.audit.append(context.Background(), entries); err != nil {
+		log.Printf("Library reload of %s not audited: %v", s.libraryPath, err)
+	}
+	log.Printf("Library reloaded from %s: %d movies (was %d)", s.libraryPath, len(movies), len(s.lib.movies))
+	s.lib.set(movies, sum)
+	s.notify(append(eventsFromAudit(entries), movieEvent{Type: eventReloaded, Time: time.Now().UTC(), Action: "reload"}))
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestServer returns a server with its files in a temporary directory
+// and an empty library.
+func newTestServer(t *testing.T) *movieLibraryServer {
+	t.Helper()
+	dir := t.TempDir()
+	genres, err := loadGenres(filepath.Join(dir, "genres.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &movieLibraryServer{
+		libraryPath: filepath.Join(dir, "library.json"),
+		genres:      genres,
+		audit:       &auditLog{path: filepath.Join(dir, "audit.jsonl")},
+		snapshots:   snapshotStore{dir: filepath.Join(dir, "snapshots")},
+	}
+	if err := s.loadLibrary(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// load calls LoadMovies with movies given as title, genre and release date.
+func load(t *testing.T, s *movieLibraryServer, movies ...catalog.Movie) {
+	t.Helper()
+	req := &pb.MovieRequest{}
+	for _, m := range movies {
+		req.Movies = append(req.Movies, m.ToProto())
+	}
+	if _, err := s.LoadMovies(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var (
+	sholay = catalog.Movie{Title: "Sholay", Genre: "action", ReleaseDate: "15-08-1975"}
+	deewar = catalog.Movie{Title: "Deewar", Genre: "crime", ReleaseDate: "24-01-1975"}
+	lagaan = catalog.Movie{Title: "Lagaan", Genre: "drama", ReleaseDate: "15-06-2001"}
+)
+
+// titles returns the titles of the library by ID.
+func titles(s *movieLibraryServer) map[int32]string {
+	m := map[int32]string{}
+	for _, movie := range s.lib.all() {
+		m[movie.ID] = movie.Title
+	}
+	return m
+}
+
+func TestReloadIgnoresOwnWrites(t *testing.T) {
+	s := newTestServer(t)
+	load(t, s, sholay)
+	first, err := os.ReadFile(s.libraryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	load(t, s, sholay, deewar)
+
+	// The watcher read the first write before the second one took the lock.
+	if err := os.WriteFile(s.libraryPath, first, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s.reloadLibrary()
+	if got := len(s.lib.all()); got != 2 {
+		t.Errorf("library has %d movies after reloading an earlier write, want 2", got)
+	}
+}
+
+func TestReloadOutsideEdit(t *testing.T) {
+	s := newTestServer(t)
+	load(t, s, sholay)
+	edit := `[{"id":1,"title":"Sholay","genre":"action","releaseDate":"15-08-1975","runtimeMinutes":204}]`
+	if err := os.WriteFile(s.libraryPath, []byte(edit), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s.reloadLibrary()
+	movies := s.lib.all()
+	if len(movies) != 1 || movies[0].RuntimeMinutes != 204 || movies[0].Version != 2 {
+		t.Errorf("library after reload = %+v, want Sholay at version 2 with a runtime", movies)
+	}
+
+	// The library the edit replaced can be restored.
+	ids, err := s.snapshots.ids()
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("snapshots %v, %v, want one", ids, err)
+	}
+	if _, err := s.RestoreSnapshot(context.Background(), &pb.RestoreSnapshotRequest{Id: ids[0]}); err != nil {
+		t.Fatal(err)
+	}
+	if movies := s.lib.all(); len(movies) != 1 || movies[0].RuntimeMinutes != 0 {
+		t.Errorf("library after restore = %+v, want Sholay without a runtime", movies)
+	}
+}
+
+func TestLoadNeverReusesIDs(t *testing.T) {
+	s := newTestServer(t)
+	load(t, s, sholay, deewar, lagaan)
+	load(t, s, sholay)
+	load(t, s, sholay, deewar)
+	want := map[int32]string{1: "Sholay", 4: "Deewar"}
+	if got := titles(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("library = %v, want %v", got, want)
+	}
+
+	// The next ID survives a restart, even once the highest ID is gone.
+	load(t, s, sholay)
+	restarted := &movieLibraryServer{
+		libraryPath: s.libraryPath,
+		genres:      s.genres,
+		audit:       s.audit,
+		snapshots:   s.snapshots,
+	}
+	if err := restarted.loadLibrary(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	load(t, restarted, sholay, lagaan)
+	want = map[int32]string{1: "Sholay", 5: "Lagaan"}
+	if got := titles(restarted); !reflect.DeepEqual(got, want) {
+		t.Errorf("library after restart = %v, want %v", got, want)
+	}
+}
+
+// versions returns the versions of the library by title.
+func versions(s *movieLibraryServer) map[string]int64 {
+	m := map[string]int64{}
+	for _, movie := range s.lib.all() {
+		m[movie.Title] = movie.Version
+	}
+	return m
+}
+
+func TestUpdateExpectedVersion(t *testing.T) {
+	s := newTestServer(t)
+	load(t, s, sholay)
+	update := func(expected int64, expectedList ...int64) error {
+		m := sholay
+		m.RuntimeMinutes = int32(200 + s.lib.all()[0].Version) // a change every time
+		_, err := s.UpdateMovieDetails(context.Background(), &pb.UpdateMovieDetailsRequest{
+			MovieId:          1,
+			UpdatedMovie:     m.ToProto(),
+			ExpectedVersion:  expected,
+			ExpectedVersions: expectedList,
+		})
+		return err
+	}
+
+	tests := []struct {
+		expected     int64
+		expectedList []int64
+		code         codes.Code
+		wantVersion  int64
+	}{
+		{0, nil, codes.OK, 2},
+		{1, nil, codes.FailedPrecondition, 2},
+		{2, nil, codes.OK, 3},
+		{0, []int64{1, 2}, codes.FailedPrecondition, 3},
+		{0, []int64{1, 3}, codes.OK, 4},
+		{3, []int64{5}, codes.FailedPrecondition, 4},
+	}
+	for _, tt := range tests {
+		err := update(tt.expected, tt.expectedList...)
+		if status.Code(err) != tt.code {
+			t.Errorf("update expecting %d, %v = %v, want %v", tt.expected, tt.expectedList, err, tt.code)
+		}
+		if got := versions(s)["Sholay"]; got != tt.wantVersion {
+			t.Errorf("update expecting %d, %v left version %d, want %d", tt.expected, tt.expectedList, got, tt.wantVersion)
+		}
+	}
+}
+
+func TestLoadKeepsVersions(t *testing.T) {
+	s := newTestServer(t)
+	load(t, s, sholay, deewar)
+	m := sholay
+	m.RuntimeMinutes = 204
+	if _, err := s.UpdateMovieDetails(context.Background(), &pb.UpdateMovieDetailsRequest{MovieId: 1, UpdatedMovie: m.ToProto()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged movies keep their version, changed ones get the next, new
+	// ones start at 1.
+	d := deewar
+	d.Synopsis = "A police officer confronts his brother."
+	load(t, s, m, d, lagaan)
+	want := map[string]int64{"Sholay": 2, "Deewar": 2, "Lagaan": 1}
+	if got := versions(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("versions after load = %v, want %v", got, want)
+	}
+
+	// Versions in the file survive a restart.
+	restarted := &movieLibraryServer{libraryPath: s.libraryPath, genres: s.genres, audit: s.audit, snapshots: s.snapshots}
+	if err := restarted.loadLibrary(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(restarted); !reflect.DeepEqual(got, want) {
+		t.Errorf("versions after restart = %v, want %v", got, want)
+	}
+}
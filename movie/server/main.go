@@ -2,40 +2,60 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
 
+	"movie/catalog"
+	"movie/config"
 	pb "movie/proto"
+	"movie/telemetry"
 
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
 
-type Movie struct {
-	Title       string `json:"title"`
-	Genre       string `json:"genre"`
-	ReleaseDate string `json:"releaseDate"`
-}
-
 type movieLibraryServer struct {
 	pb.UnimplementedMovieLibraryServiceServer // Embed the "unimplemented" gRPC server
-	movies                                    []*pb.Movie
+	lib                                       library
+	libraryPath                               string
+	posters                                   PosterStore
+	maxPosterBytes                            int64
+	genres                                    *genreStore
+	strictGenres                              bool
+	fuzzyThreshold                            float64
+	audit                                     *auditLog
+	snapshots                                 snapshotStore
+	events                                    eventBus
+	webhooks                                  *webhookStore
 }
 
 // u2
 func (s *movieLibraryServer) LoadMovies(ctx context.Context, req *pb.MovieRequest) (*pb.MovieResponse, error) {
-	// Reset the movie library by overwriting the existing movies.
-	s.movies = req.Movies
+	// Reset the movie library by overwriting the existing movies. Movies
+	// already in the library keep their ID and poster; see loadedLibrary.
+	movies := make([]catalog.Movie, len(req.Movies))
+	for i, m := range req.Movies {
+		movies[i] = catalog.FromProto(m)
+		if err := s.normalizeMovie(&movies[i]); err != nil {
+			return nil, err
+		}
+	}
+	if req.GetDryRun() {
+		return s.previewLoad(movies)
+	}
 
-	JsonFilePath := os.Getenv("JSON_FILE_PATH")
-	f, _ := os.Create(JsonFilePath)
-	data, _ := json.Marshal(req.Movies)
-	_ = os.WriteFile(f.Name(), data, 0644)
+	if _, err := s.saveLibrary(ctx, func(current []catalog.Movie) ([]catalog.Movie, error) {
+		return loadedLibrary(current, movies, s.lib.nextID)
+	}); err != nil {
+		return nil, err
+	}
 
 	return &pb.MovieResponse{
 		StatusCode: http.StatusResetContent,
@@ -44,32 +64,16 @@ func (s *movieLibraryServer) LoadMovies(ctx context.Context, req *pb.MovieReques
 
 // u3
 func (s *movieLibraryServer) GetMovieDetails(ctx context.Context, req *pb.GetMovieDetailsRequest) (*pb.GetMovieDetailsResponse, error) {
-	releaseDate := req.ReleaseDate
-
-	JsonFilePath := os.Getenv("JSON_FILE_PATH")
-	data, err := os.ReadFile(JsonFilePath)
+	query, err := newMovieQuery(req, s.genres.taxonomy())
 	if err != nil {
-		fmt.Println("Error reading JSON file:", err)
-		os.Exit(1)
+		return nil, err
 	}
 
-	// Parse the JSON data into a slice of Movie structs
-	var movies []Movie
-	if err := json.Unmarshal(data, &movies); err != nil {
-		fmt.Println("Error parsing JSON:", err)
-		os.Exit(1)
-	}
-
-	// Query movies based on the provided release date
+	// Query movies based on the provided release date and filters
 	var matchingMovies []*pb.Movie
-	for _, movie := range movies {
-		if releaseDate == "" || releaseDate == movie.ReleaseDate {
-			// Construct a pb.Movie for each matching movie
-			matchingMovies = append(matchingMovies, &pb.Movie{
-				Title:       movie.Title,
-				Genre:       movie.Genre,
-				ReleaseDate: movie.ReleaseDate,
-			})
+	for _, movie := range s.lib.all() {
+		if query.match(movie) {
+			matchingMovies = append(matchingMovies, movie.ToProto())
 		}
 	}
 
@@ -83,71 +87,93 @@ func (s *movieLibraryServer) GetMovieDetails(ctx context.Context, req *pb.GetMov
 }
 
 // u4
-var movieDataMap = map[int32]*pb.Movie{
-	1: {
-		Title:       "Betty-1",
-		Genre:       "crime",
-		ReleaseDate: "01-10-2023",
-	},
-}
-
 func (s *movieLibraryServer) UpdateMovieDetails(ctx context.Context, req *pb.UpdateMovieDetailsRequest) (*pb.UpdateMovieDetailsResponse, error) {
-	movieID := req.MovieId
-
-	JsonFilePath := os.Getenv("JSON_FILE_PATH")
-	data, err := os.ReadFile(JsonFilePath)
+	var updated catalog.Movie
+	err := s.modifyLibrary(ctx, func(movies []catalog.Movie) ([]catalog.Movie, error) {
+		var err error
+		updated, err = s.applyUpdate(movies, req.MovieId, catalog.FromProto(req.UpdatedMovie), nil, expectedVersions(req.GetExpectedVersion(), req.GetExpectedVersions()...))
+		if err != nil {
+			return nil, err
+		}
+		return movies, nil
+	})
 	if err != nil {
-		fmt.Println("Error reading JSON file:", err)
-		os.Exit(1)
+		return nil, err
 	}
 
-	var movies []map[string]interface{}
-	if err := json.Unmarshal([]byte(data), &movies); err != nil {
-		fmt.Println("Error parsing JSON:", err)
+	// Respond with the updated movie
+	return &pb.UpdateMovieDetailsResponse{
+		StatusCode:   201,
+		UpdatedMovie: updated.ToProto(),
+	}, nil
+}
 
+// main
+func main() {
+	// Settings from .env are picked up as environment variables; variables
+	// already set in the environment take precedence.
+	godotenv.Load(".env")
+
+	cfg := defaultConfig()
+	if err := config.Load("server", "MOVIE_SERVER_CONFIG", &cfg, os.Args[1:]); err != nil {
+		if errors.Is(err, config.ErrPrinted) || errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Fatalf("Invalid configuration: %v", err)
 	}
 
-	// Update the second element
-	if len(movies) >= 0 {
-		movieID = movieID - 1
-		movies[movieID]["title"] = req.UpdatedMovie.Title
-		movies[movieID]["genre"] = req.UpdatedMovie.Genre
-		movies[movieID]["releaseDate"] = req.UpdatedMovie.ReleaseDate
+	shutdown, err := telemetry.Setup(context.Background(), "movie-library-server", cfg.TraceExporter)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
 	}
+	defer shutdown(context.Background())
 
-	// Marshal the updated data back to JSON
-	updatedJSON, err := json.Marshal(movies)
+	listen, err := net.Listen("tcp", cfg.Addr)
 	if err != nil {
-		fmt.Println("Error marshaling JSON:", err)
+		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	f, _ := os.Open(JsonFilePath)
-	_ = os.WriteFile(f.Name(), updatedJSON, 0644)
+	log.Printf("Library file: %s", cfg.LibraryPath)
 
-	// Respond with the updated movie
-	return &pb.UpdateMovieDetailsResponse{
-		StatusCode:   201,
-		UpdatedMovie: req.UpdatedMovie,
-	}, nil
-}
+	genres, err := loadGenres(cfg.GenresPath)
+	if err != nil {
+		log.Fatalf("Failed to load genres: %v", err)
+	}
 
-// main
-func main() {
-	listen, err := net.Listen("tcp", ":50051")
+	webhooks, err := loadWebhooks(cfg.WebhookDir, cfg.WebhookTries, cfg.WebhookTimeout)
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		log.Fatalf("Failed to load webhooks: %v", err)
 	}
 
-	godotenv.Load(".env")
-	fmt.Println(os.Getenv("JSON_FILE_PATH"))
+	srv := &movieLibraryServer{
+		libraryPath:    cfg.LibraryPath,
+		posters:        fsPosterStore{dir: cfg.PosterDir},
+		maxPosterBytes: int64(cfg.MaxPosterBytes),
+		genres:         genres,
+		strictGenres:   cfg.StrictGenres,
+		fuzzyThreshold: cfg.FuzzyThreshold,
+		audit:          &auditLog{path: cfg.AuditPath},
+		snapshots:      snapshotStore{dir: cfg.SnapshotDir, keep: cfg.SnapshotKeep, maxAge: cfg.SnapshotMaxAge},
+		webhooks:       webhooks,
+	}
+	if err := srv.loadLibrary(context.Background()); err != nil {
+		log.Fatalf("Failed to load library: %v", err)
+	}
+	srv.webhooks.start(context.Background())
+	if cfg.WatchInterval > 0 {
+		go srv.watchLibrary(context.Background(), cfg.WatchInterval)
+	}
 
-	server := grpc.NewServer()
-	pb.RegisterMovieLibraryServiceServer(server, &movieLibraryServer{})
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.UnaryInterceptor(newIdempotencyCache(cfg.IdempotencyTTL, cfg.IdempotencyMax).unaryInterceptor),
+	)
+	pb.RegisterMovieLibraryServiceServer(server, srv)
 
 	// Enable reflection for tools like grpcurl
 	reflection.Register(server)
 
-	fmt.Println("Movie Library gRPC server started on :50051")
+	fmt.Printf("Movie Library gRPC server started on %s\n", listen.Addr())
 	if err := server.Serve(listen); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
 	}
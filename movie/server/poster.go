@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// posterChunkSize is the size of the image chunks sent by GetPoster.
+const posterChunkSize = 32 << 10
+
+var errPosterTooLarge = errors.New("poster too large")
+
+// Poster describes a stored poster image.
+type Poster struct {
+	ContentType string    `json:"contentType"`
+	ETag        string    `json:"etag"`
+	Size        int64     `json:"size"`
+	Modified    time.Time `json:"modified"`
+}
+
+// PosterStore keeps one poster image per movie ID. Get returns an error
+// wrapping fs.ErrNotExist when the movie has no poster.
+type PosterStore interface {
+	Put(ctx context.Context, movieID int32, contentType string, r io.Reader) (Poster, error)
+	Get(ctx context.Context, movieID int32) (io.ReadCloser, Poster, error)
+}
+
+// fsPosterStore keeps posters in a local directory, as <id> for the image
+// and <id>.json for its Poster description.
+type fsPosterStore struct {
+	dir string
+}
+
+func (p fsPosterStore) imagePath(id int32) string {
+	return filepath.Join(p.dir, fmt.Sprint(id))
+}
+
+func (p fsPosterStore) Put(ctx context.Context, movieID int32, contentType string, r io.Reader) (Poster, error) {
+	_, span := tracer.Start(ctx, "poster.put", trace.WithAttributes(
+		attribute.Int("movie.id", int(movieID)),
+	))
+	defer span.End()
+
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return Poster{}, err
+	}
+	tmp, err := os.CreateTemp(p.dir, "upload-*.tmp")
+	if err != nil {
+		return Poster{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		tmp.Close()
+		return Poster{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return Poster{}, err
+	}
+
+	poster := Poster{
+		ContentType: contentType,
+		ETag:        hex.EncodeToString(h.Sum(nil))[:32],
+		Size:        size,
+		Modified:    time.Now().UTC().Truncate(time.Second),
+	}
+	meta, err := json.Marshal(poster)
+	if err != nil {
+		return Poster{}, err
+	}
+	if err := os.Rename(tmp.Name(), p.imagePath(movieID)); err != nil {
+		return Poster{}, err
+	}
+	if err := writeFileAtomic(p.imagePath(movieID)+".json", meta, 0644); err != nil {
+		return Poster{}, err
+	}
+	span.SetAttributes(attribute.Int64("poster.size", size))
+	return poster, nil
+}
+
+func (p fsPosterStore) Get(ctx context.Context, movieID int32) (io.ReadCloser, Poster, error) {
+	_, span := tracer.Start(ctx, "poster.get", trace.WithAttributes(
+		attribute.Int("movie.id", int(movieID)),
+	))
+	defer span.End()
+
+	var poster Poster
+	meta, err := os.ReadFile(p.imagePath(movieID) + ".json")
+	if err != nil {
+		return nil, Poster{}, err
+	}
+	if err := json.Unmarshal(meta, &poster); err != nil {
+		return nil, Poster{}, err
+	}
+	f, err := os.Open(p.imagePath(movieID))
+	if err != nil {
+		return nil, Poster{}, err
+	}
+	return f, poster, nil
+}
+
+func posterURL(movieID int32) string {
+	return fmt.Sprintf("/movie-library/movies/%d/poster", movieID)
+}
+
+func posterToProto(movieID int32, p Poster) *pb.PosterInfo {
+	return &pb.PosterInfo{
+		MovieId:      movieID,
+		ContentType:  p.ContentType,
+		Etag:         p.ETag,
+		Size:         p.Size,
+		ModifiedUnix: p.Modified.Unix(),
+	}
+}
+
+// chunkReader reads the image bytes of an UploadPoster stream, failing with
+// errPosterTooLarge once more than max bytes have arrived.
+type chunkReader struct {
+	stream pb.MovieLibraryService_UploadPosterServer
+	buf    []byte
+	n, max int64
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		req, err := c.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		c.buf = req.GetChunk()
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	c.n += int64(n)
+	if c.n > c.max {
+		return n, errPosterTooLarge
+	}
+	return n, nil
+}
+
+func (s *movieLibraryServer) UploadPoster(stream pb.MovieLibraryService_UploadPosterServer) error {
+	ctx := stream.Context()
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	info := first.GetInfo()
+	if info == nil {
+		return status.Error(codes.InvalidArgument, "first message must carry the poster info")
+	}
+	if indexOf(s.lib.all(), info.MovieId) < 0 {
+		return status.Errorf(codes.NotFound, "movie %d not found", info.MovieId)
+	}
+
+	r := bufio.NewReaderSize(&chunkReader{stream: stream, max: s.maxPosterBytes}, 512)
+	head, err := r.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return posterError(err)
+	}
+	if len(head) == 0 {
+		return status.Error(codes.InvalidArgument, "poster is empty")
+	}
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(head)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return status.Errorf(codes.InvalidArgument, "poster must be an image, got %s", contentType)
+	}
+
+	poster, err := s.posters.Put(ctx, info.MovieId, contentType, r)
+	if err != nil {
+		return posterError(err)
+	}
+
+	url := posterURL(info.MovieId)
+	err = s.modifyLibrary(ctx, func(movies []catalog.Movie) ([]catalog.Movie, error) {
+		idx := indexOf(movies, info.MovieId)
+		if idx < 0 {
+			return nil, status.Errorf(codes.NotFound, "movie %d not found", info.MovieId)
+		}
+		movies[idx].PosterURL = url
+		return movies, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(&pb.UploadPosterResponse{
+		StatusCode: http.StatusCreated,
+		Poster:     posterToProto(info.MovieId, poster),
+		PosterUrl:  url,
+	})
+}
+
+func (s *movieLibraryServer) GetPoster(req *pb.GetPosterRequest, stream pb.MovieLibraryService_GetPosterServer) error {
+	movies := s.lib.all()
+	idx := indexOf(movies, req.MovieId)
+	if idx < 0 || movies[idx].PosterURL == "" {
+		return status.Errorf(codes.NotFound, "no poster for movie %d", req.MovieId)
+	}
+
+	rc, poster, err := s.posters.Get(stream.Context(), req.MovieId)
+	if errors.Is(err, fs.ErrNotExist) {
+		return status.Errorf(codes.NotFound, "no poster for movie %d", req.MovieId)
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal, "read poster: %v", err)
+	}
+	defer rc.Close()
+
+	if err := stream.Send(&pb.GetPosterResponse{
+		Data: &pb.GetPosterResponse_Info{Info: posterToProto(req.MovieId, poster)},
+	}); err != nil {
+		return err
+	}
+	buf := make([]byte, posterChunkSize)
+	for {
+		n, err := rc.Read(buf)
+		if n > 0 {
+			if err := stream.Send(&pb.GetPosterResponse{
+				Data: &pb.GetPosterResponse_Chunk{Chunk: buf[:n]},
+			}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "read poster: %v", err)
+		}
+	}
+}
+
+// posterError maps a failed upload to a gRPC status.
+func posterError(err error) error {
+	if errors.Is(err, errPosterTooLarge) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Errorf(codes.Internal, "store poster: %v", err)
+}
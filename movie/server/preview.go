@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+
+	"movie/catalog"
+	pb "movie/proto"
+)
+
+// previewLoad answers a dry run of LoadMovies with the changes the load
+// would make to the library, which is left alone. Movies are matched and
+// changes found as for the audit log of a real load.
+func (s *movieLibraryServer) previewLoad(movies []catalog.Movie) (*pb.MovieResponse, error) {
+	current, next := s.lib.current()
+	after, err := loadedLibrary(current, movies, next)
+	if err != nil {
+		return nil, err
+	}
+	setVersions(current, after)
+
+	p := &pb.LoadPreview{}
+	for _, e := range diffLibraries(current, after) {
+		switch e.Op {
+		case opCreate:
+			p.AddedMovies = append(p.AddedMovies, e.After.ToProto())
+		case opDelete:
+			p.RemovedMovies = append(p.RemovedMovies, e.Before.ToProto())
+		case opUpdate:
+			mod := &pb.MovieModification{Before: e.Before.ToProto(), After: e.After.ToProto()}
+			for _, c := range e.Changes {
+				mod.Changes = append(mod.Changes, &pb.FieldChange{Field: c.Field, Old: c.Old, New: c.New})
+			}
+			p.ModifiedMovies = append(p.ModifiedMovies, mod)
+		}
+	}
+	p.Added = int32(len(p.AddedMovies))
+	p.Removed = int32(len(p.RemovedMovies))
+	p.Modified = int32(len(p.ModifiedMovies))
+	p.Unchanged = int32(len(after)) - p.Added - p.Modified
+	return &pb.MovieResponse{StatusCode: http.StatusOK, Preview: p}, nil
+}
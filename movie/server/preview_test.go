@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPreviewMatchesLoad(t *testing.T) {
+	s := newTestServer(t)
+	load(t, s, sholay, deewar, lagaan)
+	load(t, s, sholay, deewar) // Lagaan's ID is not handed out again
+
+	changed := sholay
+	changed.Title, changed.RuntimeMinutes = "SHOLAY", 204
+	iqbal := catalog.Movie{Title: "Iqbal", Genre: "sport", ReleaseDate: "26-08-2005"}
+	req := &pb.MovieRequest{DryRun: true}
+	for _, m := range []catalog.Movie{iqbal, changed, lagaan} {
+		req.Movies = append(req.Movies, m.ToProto())
+	}
+
+	before, err := os.ReadFile(s.libraryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.LoadMovies(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := resp.Preview
+	if p.Added != 2 || p.Removed != 1 || p.Modified != 1 || p.Unchanged != 0 {
+		t.Errorf("preview counts +%d -%d ~%d =%d, want +2 -1 ~1 =0", p.Added, p.Removed, p.Modified, p.Unchanged)
+	}
+	after, err := os.ReadFile(s.libraryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Error("library file changed by a dry run")
+	}
+
+	req.DryRun = false
+	if _, err := s.LoadMovies(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every movie the preview shows is stored exactly so, IDs and versions
+	// included, and those it removes are gone.
+	stored := map[int32]*pb.Movie{}
+	for _, m := range s.lib.all() {
+		stored[m.ID] = m.ToProto()
+	}
+	var want []*pb.Movie
+	want = append(want, p.AddedMovies...)
+	for _, mod := range p.ModifiedMovies {
+		want = append(want, mod.After)
+	}
+	if len(stored) != len(want) {
+		t.Errorf("library holds %d movies, preview shows %d", len(stored), len(want))
+	}
+	for _, m := range want {
+		if !proto.Equal(stored[m.Id], m) {
+			t.Errorf("stored movie %d = %v, preview shows %v", m.Id, stored[m.Id], m)
+		}
+	}
+	for _, m := range p.RemovedMovies {
+		if _, ok := stored[m.Id]; ok {
+			t.Errorf("movie %d removed by the preview is still stored", m.Id)
+		}
+	}
+	ids := map[string]int32{}
+	for _, m := range p.AddedMovies {
+		ids[m.Title] = m.Id
+	}
+	if ids["Iqbal"] != 4 || ids["Lagaan"] != 5 {
+		t.Errorf("preview added %v, want Iqbal 4 and Lagaan 5", ids)
+	}
+}
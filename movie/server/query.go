@@ -0,0 +1,96 @@
+package main
+
+import (
+	"regexp"
+	"time"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// movieQuery holds the parsed filters of a GetMovieDetailsRequest.
+type movieQuery struct {
+	tax           *catalog.Taxonomy
+	releaseDate   string
+	genres        []string
+	allGenres     bool
+	excludeGenres []string
+	title         *regexp.Regexp
+	from, to      time.Time
+}
+
+// newMovieQuery parses req, reporting bad patterns and dates as
+// codes.InvalidArgument. Genres are resolved through tax, so aliases match
+// and a genre also matches the genres below it.
+func newMovieQuery(req *pb.GetMovieDetailsRequest, tax *catalog.Taxonomy) (movieQuery, error) {
+	q := movieQuery{
+		tax:           tax,
+		releaseDate:   req.GetReleaseDate(),
+		genres:        req.GetGenres(),
+		allGenres:     req.GetAllGenres(),
+		excludeGenres: req.GetExcludeGenres(),
+	}
+
+	if p := req.GetTitlePattern(); p != "" {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return movieQuery{}, status.Errorf(codes.InvalidArgument, "title_pattern: %v", err)
+		}
+		q.title = re
+	}
+
+	var err error
+	if q.from, err = parseReleaseDate(req.GetReleasedFrom()); err != nil {
+		return movieQuery{}, status.Errorf(codes.InvalidArgument, "released_from: %v", err)
+	}
+	if q.to, err = parseReleaseDate(req.GetReleasedTo()); err != nil {
+		return movieQuery{}, status.Errorf(codes.InvalidArgument, "released_to: %v", err)
+	}
+	return q, nil
+}
+
+func parseReleaseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(catalog.ReleaseDateLayout, s)
+}
+
+// match reports whether m passes every filter of q.
+func (q movieQuery) match(m catalog.Movie) bool {
+	if q.releaseDate != "" && q.releaseDate != m.ReleaseDate {
+		return false
+	}
+	if len(q.genres) > 0 {
+		n := 0
+		for _, g := range q.genres {
+			if q.tax.HasGenre(m, g) {
+				n++
+			}
+		}
+		if n == 0 || (q.allGenres && n < len(q.genres)) {
+			return false
+		}
+	}
+	for _, g := range q.excludeGenres {
+		if q.tax.HasGenre(m, g) {
+			return false
+		}
+	}
+	if q.title != nil && !q.title.MatchString(m.Title) {
+		return false
+	}
+	if !q.from.IsZero() || !q.to.IsZero() {
+		released, err := m.Released()
+		if err != nil {
+			return false
+		}
+		if (!q.from.IsZero() && released.Before(q.from)) || (!q.to.IsZero() && released.After(q.to)) {
+			return false
+		}
+	}
+	return true
+}
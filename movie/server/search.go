@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+
+	pb "movie/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Hit limits of FullTextSearch and FuzzyTitleLookup.
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// searchLimit applies the defaults and bounds to a requested limit.
+func searchLimit(limit int32) (int, error) {
+	switch {
+	case limit < 0:
+		return 0, status.Error(codes.InvalidArgument, "limit must not be negative")
+	case limit == 0:
+		return defaultSearchLimit, nil
+	case limit > maxSearchLimit:
+		return maxSearchLimit, nil
+	}
+	return int(limit), nil
+}
+
+func (s *movieLibraryServer) FullTextSearch(ctx context.Context, req *pb.FullTextSearchRequest) (*pb.FullTextSearchResponse, error) {
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	limit, err := searchLimit(req.GetLimit())
+	if err != nil {
+		return nil, err
+	}
+
+	movies, index := s.lib.searchIndex()
+	resp := &pb.FullTextSearchResponse{}
+	if index == nil {
+		return resp, nil
+	}
+	hits, total := index.Search(req.GetQuery(), limit)
+	for _, h := range hits {
+		idx := indexOf(movies, h.ID)
+		if idx < 0 {
+			continue
+		}
+		resp.Hits = append(resp.Hits, &pb.SearchHit{
+			Movie:          movies[idx].ToProto(),
+			Score:          h.Score,
+			TitleHighlight: h.Title,
+			Snippet:        h.Snippet,
+		})
+	}
+	resp.Total = int32(total)
+	return resp, nil
+}
+
+func (s *movieLibraryServer) FuzzyTitleLookup(ctx context.Context, req *pb.FuzzyTitleLookupRequest) (*pb.FuzzyTitleLookupResponse, error) {
+	if req.GetTitle() == "" {
+		return nil, status.Error(codes.InvalidArgument, "title is required")
+	}
+	threshold := req.GetThreshold()
+	switch {
+	case threshold < 0 || threshold > 1:
+		return nil, status.Error(codes.InvalidArgument, "threshold must be between 0 and 1")
+	case threshold == 0:
+		threshold = s.fuzzyThreshold
+	}
+	limit, err := searchLimit(req.GetLimit())
+	if err != nil {
+		return nil, err
+	}
+
+	movies, index := s.lib.searchIndex()
+	resp := &pb.FuzzyTitleLookupResponse{}
+	if index == nil {
+		return resp, nil
+	}
+	for _, m := range index.FuzzyTitles(req.GetTitle(), threshold, limit) {
+		idx := indexOf(movies, m.ID)
+		if idx < 0 {
+			continue
+		}
+		resp.Matches = append(resp.Matches, &pb.TitleMatch{
+			Movie: movies[idx].ToProto(),
+			Score: m.Score,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// snapshotIDLayout names snapshots after the time they were taken, so IDs
+// sort chronologically.
+const snapshotIDLayout = "20060102T150405.000000000Z"
+
+var snapshotIDPattern = regexp.MustCompile(`^\d{8}T\d{6}\.\d{9}Z$`)
+
+// snapshot is a copy of the library as it was before a load, a restore or
+// an outside edit of the library file replaced it.
+type snapshot struct {
+	ID        string          `json:"id"`
+	Created   time.Time       `json:"created"`
+	Action    string          `json:"action"` // RPC that replaced the library, or "reload"
+	Actor     string          `json:"actor,omitempty"`
+	RequestID string          `json:"requestId,omitempty"`
+	Movies    []catalog.Movie `json:"movies"`
+}
+
+// snapshotStore keeps snapshots as <id>.json files in dir. After every new
+// snapshot it drops all but the newest keep and those older than maxAge;
+// the newest snapshot is always kept. Zero disables either limit.
+type snapshotStore struct {
+	dir    string
+	keep   int
+	maxAge time.Duration
+}
+
+func (st snapshotStore) path(id string) string {
+	return filepath.Join(st.dir, id+".json")
+}
+
+// save writes a snapshot of movies taken for the request in ctx.
+func (st snapshotStore) save(ctx context.Context, movies []catalog.Movie) (snapshot, error) {
+	action, actor, requestID := requestInfo(ctx)
+	return st.saveSnapshot(ctx, snapshot{Action: action, Actor: actor, RequestID: requestID, Movies: movies})
+}
+
+// saveSnapshot names snap after the current time, writes it and applies
+// the retention policy.
+func (st snapshotStore) saveSnapshot(ctx context.Context, snap snapshot) (snapshot, error) {
+	ctx, span := tracer.Start(ctx, "snapshot.save", trace.WithAttributes(
+		attribute.Int("library.movies", len(snap.Movies)),
+	))
+	defer span.End()
+
+	now := time.Now().UTC()
+	snap.ID, snap.Created = now.Format(snapshotIDLayout), now
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return snapshot{}, err
+	}
+	if err := os.MkdirAll(st.dir, 0755); err != nil {
+		return snapshot{}, err
+	}
+	if err := writeFileAtomic(st.path(snap.ID), data, 0644); err != nil {
+		return snapshot{}, err
+	}
+	if err := st.prune(ctx, now); err != nil {
+		log.Printf("Pruning snapshots in %s failed: %v", st.dir, err)
+	}
+	return snap, nil
+}
+
+// ids returns the snapshot IDs, newest first.
+func (st snapshotStore) ids() ([]string, error) {
+	entries, err := os.ReadDir(st.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		id := strings.TrimSuffix(e.Name(), ".json")
+		if snapshotIDPattern.MatchString(id) && e.Name() == id+".json" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+func (st snapshotStore) prune(ctx context.Context, now time.Time) error {
+	ids, err := st.ids()
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		if i == 0 {
+			continue
+		}
+		created, err := time.Parse(snapshotIDLayout, id)
+		if err != nil {
+			continue
+		}
+		if (st.keep > 0 && i >= st.keep) || (st.maxAge > 0 && now.Sub(created) > st.maxAge) {
+			if err := os.Remove(st.path(id)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// load reads the snapshot with id, reporting unknown IDs as
+// codes.NotFound.
+func (st snapshotStore) load(ctx context.Context, id string) (snapshot, error) {
+	_, span := tracer.Start(ctx, "snapshot.load")
+	defer span.End()
+
+	if !snapshotIDPattern.MatchString(id) {
+		return snapshot{}, status.Errorf(codes.NotFound, "snapshot %q not found", id)
+	}
+	data, err := os.ReadFile(st.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return snapshot{}, status.Errorf(codes.NotFound, "snapshot %q not found", id)
+	}
+	if err != nil {
+		return snapshot{}, status.Errorf(codes.Internal, "read snapshot: %v", err)
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snapshot{}, status.Errorf(codes.Internal, "snapshot %s: %v", id, err)
+	}
+	return snap, nil
+}
+
+// list returns every snapshot, newest first.
+func (st snapshotStore) list(ctx context.Context) ([]snapshot, error) {
+	ids, err := st.ids()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "list snapshots: %v", err)
+	}
+	var snaps []snapshot
+	for _, id := range ids {
+		snap, err := st.load(ctx, id)
+		if status.Code(err) == codes.NotFound {
+			continue // pruned meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+	return snaps, nil
+}
+
+func snapshotToProto(s snapshot) *pb.SnapshotInfo {
+	return &pb.SnapshotInfo{
+		Id:          s.ID,
+		CreatedUnix: s.Created.Unix(),
+		Action:      s.Action,
+		Actor:       s.Actor,
+		MovieCount:  int32(len(s.Movies)),
+	}
+}
+
+func (s *movieLibraryServer) ListSnapshots(ctx context.Context, req *pb.ListSnapshotsRequest) (*pb.ListSnapshotsResponse, error) {
+	snaps, err := s.snapshots.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.ListSnapshotsResponse{}
+	for _, snap := range snaps {
+		resp.Snapshots = append(resp.Snapshots, snapshotToProto(snap))
+	}
+	return resp, nil
+}
+
+func (s *movieLibraryServer) RestoreSnapshot(ctx context.Context, req *pb.RestoreSnapshotRequest) (*pb.RestoreSnapshotResponse, error) {
+	snap, err := s.snapshots.load(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	backup, err := s.saveLibrary(ctx, func([]catalog.Movie) ([]catalog.Movie, error) {
+		return snap.Movies, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.RestoreSnapshotResponse{
+		StatusCode: http.StatusOK,
+		Restored:   snapshotToProto(snap),
+	}
+	if backup != nil {
+		resp.Backup = snapshotToProto(*backup)
+	}
+	return resp, nil
+}
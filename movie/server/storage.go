@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("movie/server")
+
+// readLibraryFile returns the raw contents of the JSON library file.
+func (s *movieLibraryServer) readLibraryFile(ctx context.Context) ([]byte, error) {
+	path := s.libraryPath
+	_, span := tracer.Start(ctx, "storage.read", trace.WithAttributes(
+		attribute.String("file.path", path),
+	))
+	defer span.End()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("file.size", len(data)))
+	return data, nil
+}
+
+// writeLibraryFile replaces the JSON library file with data. The data is
+// written to a temporary file first and renamed into place, so readers of
+// the file never see a partial write.
+func (s *movieLibraryServer) writeLibraryFile(ctx context.Context, data []byte) error {
+	path := s.libraryPath
+	_, span := tracer.Start(ctx, "storage.write", trace.WithAttributes(
+		attribute.String("file.path", path),
+		attribute.Int("file.size", len(data)),
+	))
+	defer span.End()
+
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data, giving the file the permissions
+// perm. Readers see either the old or the new contents.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// idsPath is the file next to the library that records the next movie ID.
+func (s *movieLibraryServer) idsPath() string {
+	return s.libraryPath + ".ids.json"
+}
+
+// idsFile is the contents of the IDs file.
+type idsFile struct {
+	NextID int32 `json:"nextId"`
+}
+
+// readNextID returns the next movie ID recorded next to the library, or 0
+// when there is no record yet.
+func (s *movieLibraryServer) readNextID() (int32, error) {
+	var ids idsFile
+	if err := readJSONFile(s.idsPath(), &ids); err != nil {
+		return 0, err
+	}
+	return ids.NextID, nil
+}
+
+// saveNextID records next as the next movie ID, unless it is recorded
+// already. The caller holds the library lock.
+func (s *movieLibraryServer) saveNextID(next int32) error {
+	if next == s.lib.savedNextID {
+		return nil
+	}
+	data, err := json.Marshal(idsFile{NextID: next})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.idsPath(), data, 0644); err != nil {
+		return err
+	}
+	s.lib.savedNextID = next
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	pb "movie/proto"
+	"movie/telemetry"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// spanExporter collects the spans of the tests. The package's tracer binds
+// to the first global provider, so the provider is only set once.
+var (
+	spanExporter     = tracetest.NewInMemoryExporter()
+	spanProviderOnce sync.Once
+)
+
+// TestTraceParentage checks that a gateway request, the gRPC call it makes
+// and the library write on the server end up in one trace, each span a
+// descendant of the previous.
+func TestTraceParentage(t *testing.T) {
+	spanProviderOnce.Do(func() {
+		otel.SetTracerProvider(telemetry.NewProvider("movie-library-test", spanExporter))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	exp := spanExporter
+	exp.Reset()
+
+	dir := t.TempDir()
+	genres, err := loadGenres(filepath.Join(dir, "genres.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &movieLibraryServer{
+		libraryPath: filepath.Join(dir, "library.json"),
+		genres:      genres,
+		audit:       &auditLog{path: filepath.Join(dir, "audit.jsonl")},
+		snapshots:   snapshotStore{dir: filepath.Join(dir, "snapshots")},
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	pb.RegisterMovieLibraryServiceServer(server, srv)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := pb.NewMovieLibraryServiceClient(conn)
+
+	// A stand-in for the gateway: an instrumented handler calling LoadMovies.
+	gateway := httptest.NewServer(otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := client.LoadMovies(r.Context(), &pb.MovieRequest{Movies: []*pb.Movie{
+			{Title: "Lagaan", Genre: "drama", ReleaseDate: "15-06-2001"},
+		}})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		}
+	}), "/movie-library/load"))
+	defer gateway.Close()
+
+	resp, err := http.Post(gateway.URL, "application/xml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("gateway responded %s", resp.Status)
+	}
+
+	spans := exp.GetSpans()
+	byID := map[trace.SpanID]tracetest.SpanStub{}
+	for _, s := range spans {
+		byID[s.SpanContext.SpanID()] = s
+	}
+	find := func(name string, kind trace.SpanKind) tracetest.SpanStub {
+		t.Helper()
+		for _, s := range spans {
+			if s.Name == name && s.SpanKind == kind {
+				return s
+			}
+		}
+		t.Fatalf("no %s span %q among %d spans", kind, name, len(spans))
+		return tracetest.SpanStub{}
+	}
+	// isAncestor reports whether a is an ancestor of s.
+	isAncestor := func(a, s tracetest.SpanStub) bool {
+		for s.Parent.IsValid() {
+			if s.Parent.SpanID() == a.SpanContext.SpanID() {
+				return true
+			}
+			var ok bool
+			if s, ok = byID[s.Parent.SpanID()]; !ok {
+				return false
+			}
+		}
+		return false
+	}
+
+	const method = "movie_library.MovieLibraryService/LoadMovies"
+	chain := []tracetest.SpanStub{
+		find("/movie-library/load", trace.SpanKindServer),
+		find(method, trace.SpanKindClient),
+		find(method, trace.SpanKindServer),
+		find("storage.write", trace.SpanKindInternal),
+	}
+	for i := 1; i < len(chain); i++ {
+		if chain[i].SpanContext.TraceID() != chain[0].SpanContext.TraceID() {
+			t.Errorf("%s is in trace %s, want %s", chain[i].Name, chain[i].SpanContext.TraceID(), chain[0].SpanContext.TraceID())
+		}
+		if !isAncestor(chain[i-1], chain[i]) {
+			t.Errorf("%s (%s) does not descend from %s (%s)", chain[i].Name, chain[i].SpanKind, chain[i-1].Name, chain[i-1].SpanKind)
+		}
+	}
+}
@@ -0,0 +1,607 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	pb "movie/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Files in the webhook directory.
+const (
+	webhooksFile    = "webhooks.json"      // subscriptions, including secrets
+	queueFile       = "queue.json"         // deliveries not yet made
+	deliveriesFile  = "deliveries.jsonl"   // every delivery attempt
+	deadLettersFile = "dead-letters.jsonl" // deliveries that ran out of attempts
+)
+
+// Delivery statuses.
+const (
+	deliveryDelivered = "delivered"
+	deliveryRetrying  = "retrying"
+	deliveryDead      = "dead"
+)
+
+const (
+	// webhookBackoff is the wait before the second attempt; it doubles
+	// with every further attempt, up to maxWebhookBackoff.
+	webhookBackoff    = 2 * time.Second
+	maxWebhookBackoff = time.Hour
+	// maxWebhookResponse bounds how much of a response body is read.
+	maxWebhookResponse = 64 << 10
+	// webhookBatches is how many published batches of events may wait to
+	// be queued before enqueue blocks.
+	webhookBatches = 256
+)
+
+// webhook is a subscription as stored in webhooksFile.
+type webhook struct {
+	ID      string    `json:"id"`
+	URL     string    `json:"url"`
+	Events  []string  `json:"events,omitempty"`
+	Secret  string    `json:"secret"`
+	Created time.Time `json:"created"`
+}
+
+func (h webhook) wants(eventType string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// delivery is a queued event for one webhook.
+type delivery struct {
+	ID        string          `json:"id"`
+	WebhookID string          `json:"webhookId"`
+	Seq       uint64          `json:"seq"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"` // made so far
+	Next      time.Time       `json:"next"`     // earliest time of the next attempt
+}
+
+// deliveryRecord is a line of the delivery log.
+type deliveryRecord struct {
+	Time       time.Time `json:"time"`
+	DeliveryID string    `json:"deliveryId"`
+	WebhookID  string    `json:"webhookId"`
+	Seq        uint64    `json:"seq"`
+	Type       string    `json:"type"`
+	Attempt    int       `json:"attempt"`
+	Status     string    `json:"status"`
+	HTTPStatus int       `json:"httpStatus,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// webhookStore keeps the webhook subscriptions and a queue of deliveries in
+// dir, so deliveries survive restarts. Every webhook has its own worker,
+// which makes its deliveries one at a time in the order they were queued,
+// so a slow receiver holds up only its own deliveries. A failed delivery is
+// retried with exponential backoff and moved to the dead letters after
+// maxAttempts attempts.
+type webhookStore struct {
+	dir         string
+	maxAttempts int
+	backoff     time.Duration // wait before the second attempt
+	client      *http.Client
+	incoming    chan []movieEvent // events for the queue writer
+
+	mu      sync.Mutex
+	hooks   []webhook
+	queue   []delivery
+	ctx     context.Context // set by start
+	workers map[string]*webhookWorker
+}
+
+// webhookWorker delivers the events of one webhook.
+type webhookWorker struct {
+	wake   chan struct{} // signalled when deliveries are queued
+	cancel context.CancelFunc
+}
+
+// loadWebhooks reads the subscriptions and pending deliveries from dir.
+func loadWebhooks(dir string, maxAttempts int, timeout time.Duration) (*webhookStore, error) {
+	st := &webhookStore{
+		dir:         dir,
+		maxAttempts: maxAttempts,
+		backoff:     webhookBackoff,
+		client:      &http.Client{Timeout: timeout},
+		incoming:    make(chan []movieEvent, webhookBatches),
+		workers:     map[string]*webhookWorker{},
+	}
+	if err := readJSONFile(filepath.Join(dir, webhooksFile), &st.hooks); err != nil {
+		return nil, err
+	}
+	if err := readJSONFile(filepath.Join(dir, queueFile), &st.queue); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// readJSONFile decodes path into v, leaving v alone when there is no file.
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// save writes v to name in the webhook directory. The directory and its
+// files are only accessible to the server's user, as the subscriptions hold
+// secrets. v is not indented, which would reformat the queued payloads.
+func (st *webhookStore) save(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(st.dir, 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(st.dir, name), data, 0600)
+}
+
+// appendLine appends v as a JSON line to name in the webhook directory.
+func (st *webhookStore) appendLine(name string, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(st.dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(st.dir, name), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func newWebhookID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// register adds hook, saves the subscriptions and starts its worker.
+func (st *webhookStore) register(hook webhook) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	hooks := append(st.hooks[:len(st.hooks):len(st.hooks)], hook)
+	if err := st.save(webhooksFile, hooks); err != nil {
+		return status.Errorf(codes.Internal, "save webhooks: %v", err)
+	}
+	st.hooks = hooks
+	st.startWorker(hook.ID)
+	return nil
+}
+
+// remove deletes the webhook with id and its queued deliveries. It reports
+// whether the webhook existed.
+func (st *webhookStore) remove(id string) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var hooks []webhook
+	for _, h := range st.hooks {
+		if h.ID != id {
+			hooks = append(hooks, h)
+		}
+	}
+	if len(hooks) == len(st.hooks) {
+		return false, nil
+	}
+	if err := st.save(webhooksFile, hooks); err != nil {
+		return false, status.Errorf(codes.Internal, "save webhooks: %v", err)
+	}
+	st.hooks = hooks
+	if w, ok := st.workers[id]; ok {
+		w.cancel()
+		delete(st.workers, id)
+	}
+
+	var queue []delivery
+	for _, d := range st.queue {
+		if d.WebhookID != id {
+			queue = append(queue, d)
+		}
+	}
+	st.queue = queue
+	if err := st.save(queueFile, st.queue); err != nil {
+		log.Printf("Saving webhook queue failed: %v", err)
+	}
+	return true, nil
+}
+
+func (st *webhookStore) list() []webhook {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.hooks
+}
+
+// enqueue hands events to the queue writer started by start, which queues
+// them for every webhook that wants them. events must be numbered already.
+// Callers hold the library lock, so the queue file is not written here;
+// enqueue only blocks when webhookBatches batches are still waiting.
+func (st *webhookStore) enqueue(events []movieEvent) {
+	st.incoming <- events
+}
+
+// writeQueue queues the events handed to enqueue until ctx is done, and
+// then those still waiting.
+func (st *webhookStore) writeQueue(ctx context.Context) {
+	for {
+		select {
+		case events := <-st.incoming:
+			st.queueEvents(events)
+		case <-ctx.Done():
+			for {
+				select {
+				case events := <-st.incoming:
+					st.queueEvents(events)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// queueEvents queues events for every webhook that wants them, saves the
+// queue and wakes the workers concerned.
+func (st *webhookStore) queueEvents(events []movieEvent) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	queued := map[string]bool{}
+	for _, ev := range events {
+		var payload []byte
+		for _, h := range st.hooks {
+			if !h.wants(ev.Type) {
+				continue
+			}
+			if payload == nil {
+				var err error
+				if payload, err = json.Marshal(eventToProto(ev)); err != nil {
+					log.Printf("Encoding event %d for webhooks failed: %v", ev.Seq, err)
+					break
+				}
+			}
+			st.queue = append(st.queue, delivery{
+				ID:        newWebhookID(),
+				WebhookID: h.ID,
+				Seq:       ev.Seq,
+				Type:      ev.Type,
+				Payload:   payload,
+				Next:      time.Now(),
+			})
+			queued[h.ID] = true
+		}
+	}
+	if len(queued) == 0 {
+		return
+	}
+	if err := st.save(queueFile, st.queue); err != nil {
+		log.Printf("Saving webhook queue failed: %v", err)
+	}
+	for id := range queued {
+		if w, ok := st.workers[id]; ok {
+			select {
+			case w.wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// start starts the queue writer and a worker for every webhook, and for
+// those registered later, which run until ctx is done.
+func (st *webhookStore) start(ctx context.Context) {
+	go st.writeQueue(ctx)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.ctx = ctx
+	for _, h := range st.hooks {
+		st.startWorker(h.ID)
+	}
+}
+
+// startWorker starts the worker of the webhook with id, unless start has
+// not been called yet. st.mu must be held.
+func (st *webhookStore) startWorker(id string) {
+	if st.ctx == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(st.ctx)
+	w := &webhookWorker{wake: make(chan struct{}, 1), cancel: cancel}
+	st.workers[id] = w
+	go st.deliver(ctx, id, w.wake)
+}
+
+// deliver makes the queued deliveries of the webhook with id as they fall
+// due, until ctx is done.
+func (st *webhookStore) deliver(ctx context.Context, id string, wake <-chan struct{}) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for ctx.Err() == nil {
+		d, hook, wait := st.next(id, time.Now())
+		if d != nil {
+			st.attempt(ctx, *d, hook)
+			continue
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// next returns the first delivery for the webhook with id that is due, or
+// how long to wait for one.
+func (st *webhookStore) next(id string, now time.Time) (*delivery, webhook, time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var hook webhook
+	for _, h := range st.hooks {
+		if h.ID == id {
+			hook = h
+		}
+	}
+	wait := maxWebhookBackoff
+	for _, d := range st.queue {
+		if d.WebhookID != id {
+			continue
+		}
+		if d.Next.After(now) {
+			if w := d.Next.Sub(now); w < wait {
+				wait = w
+			}
+			continue
+		}
+		return &d, hook, 0
+	}
+	return nil, webhook{}, wait
+}
+
+// attempt delivers d to hook once and records the outcome.
+func (st *webhookStore) attempt(ctx context.Context, d delivery, hook webhook) {
+	d.Attempts++
+	rec := deliveryRecord{
+		Time:       time.Now().UTC(),
+		DeliveryID: d.ID,
+		WebhookID:  d.WebhookID,
+		Seq:        d.Seq,
+		Type:       d.Type,
+		Attempt:    d.Attempts,
+	}
+	code, err := st.post(ctx, d, hook)
+	if ctx.Err() != nil {
+		// The webhook was deleted or the server is stopping.
+		return
+	}
+	rec.HTTPStatus = code
+	switch {
+	case err == nil:
+		rec.Status = deliveryDelivered
+	case d.Attempts >= st.maxAttempts:
+		rec.Status, rec.Error = deliveryDead, err.Error()
+		if err := st.appendLine(deadLettersFile, d); err != nil {
+			log.Printf("Dead-lettering webhook delivery %s failed: %v", d.ID, err)
+		}
+	default:
+		rec.Status, rec.Error = deliveryRetrying, err.Error()
+		backoff := st.backoff << (d.Attempts - 1)
+		if backoff <= 0 || backoff > maxWebhookBackoff {
+			backoff = maxWebhookBackoff
+		}
+		d.Next = time.Now().Add(backoff)
+	}
+	if err := st.appendLine(deliveriesFile, rec); err != nil {
+		log.Printf("Logging webhook delivery %s failed: %v", d.ID, err)
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for i := range st.queue {
+		if st.queue[i].ID != d.ID {
+			continue
+		}
+		if rec.Status == deliveryRetrying {
+			st.queue[i] = d
+		} else {
+			st.queue = append(st.queue[:i:i], st.queue[i+1:]...)
+		}
+		break
+	}
+	if err := st.save(queueFile, st.queue); err != nil {
+		log.Printf("Saving webhook queue failed: %v", err)
+	}
+}
+
+// post sends d to hook. Any response but 2xx is an error.
+func (st *webhookStore) post(ctx context.Context, d delivery, hook webhook) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write(d.Payload)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Movie-Event", d.Type)
+	req.Header.Set("X-Movie-Delivery", d.ID)
+	req.Header.Set("X-Movie-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := st.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookResponse))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// deliveries returns the logged attempts for webhookID in the order they
+// were made, at most the last limit of them when limit is positive.
+func (st *webhookStore) deliveries(webhookID string, limit int) ([]deliveryRecord, error) {
+	f, err := os.Open(filepath.Join(st.dir, deliveriesFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recs []deliveryRecord
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var rec deliveryRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, err
+		}
+		if rec.WebhookID != webhookID {
+			continue
+		}
+		recs = append(recs, rec)
+		if limit > 0 && len(recs) > limit {
+			recs = recs[1:]
+		}
+	}
+	return recs, sc.Err()
+}
+
+func webhookToProto(h webhook) *pb.Webhook {
+	return &pb.Webhook{
+		Id:          h.ID,
+		Url:         h.URL,
+		Events:      h.Events,
+		CreatedUnix: h.Created.Unix(),
+	}
+}
+
+func (s *movieLibraryServer) RegisterWebhook(ctx context.Context, req *pb.RegisterWebhookRequest) (*pb.RegisterWebhookResponse, error) {
+	u, err := url.Parse(req.GetUrl())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "url %q is not an absolute http or https URL", req.GetUrl())
+	}
+	if req.GetSecret() == "" {
+		return nil, status.Error(codes.InvalidArgument, "secret is required")
+	}
+	for _, e := range req.GetEvents() {
+		switch e {
+		case eventCreated, eventUpdated, eventDeleted, eventReloaded:
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unknown event type %q", e)
+		}
+	}
+
+	hook := webhook{
+		ID:      newWebhookID(),
+		URL:     u.String(),
+		Events:  req.GetEvents(),
+		Secret:  req.GetSecret(),
+		Created: time.Now().UTC(),
+	}
+	if err := s.webhooks.register(hook); err != nil {
+		return nil, err
+	}
+	return &pb.RegisterWebhookResponse{
+		StatusCode: http.StatusCreated,
+		Webhook:    webhookToProto(hook),
+	}, nil
+}
+
+func (s *movieLibraryServer) ListWebhooks(ctx context.Context, req *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	resp := &pb.ListWebhooksResponse{}
+	for _, h := range s.webhooks.list() {
+		resp.Webhooks = append(resp.Webhooks, webhookToProto(h))
+	}
+	return resp, nil
+}
+
+func (s *movieLibraryServer) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error) {
+	found, err := s.webhooks.remove(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "webhook %q not found", req.GetId())
+	}
+	return &pb.DeleteWebhookResponse{StatusCode: http.StatusOK}, nil
+}
+
+func (s *movieLibraryServer) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	if req.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+	recs, err := s.webhooks.deliveries(req.GetWebhookId(), int(req.GetLimit()))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "read delivery log: %v", err)
+	}
+	resp := &pb.ListWebhookDeliveriesResponse{}
+	for _, r := range recs {
+		resp.Deliveries = append(resp.Deliveries, &pb.WebhookDelivery{
+			DeliveryId: r.DeliveryID,
+			WebhookId:  r.WebhookID,
+			EventSeq:   r.Seq,
+			EventType:  r.Type,
+			Attempt:    int32(r.Attempt),
+			TimeUnix:   r.Time.Unix(),
+			Status:     r.Status,
+			HttpStatus: int32(r.HTTPStatus),
+			Error:      r.Error,
+		})
+	}
+	return resp, nil
+}
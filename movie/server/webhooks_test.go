@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// receivedHook is a request made to a test receiver.
+type receivedHook struct {
+	time   time.Time
+	header http.Header
+	body   []byte
+}
+
+// newReceiver starts a webhook receiver that reports every request on the
+// returned channel and responds with the status codes in turn, repeating the
+// last one.
+func newReceiver(t *testing.T, codes ...int) (*httptest.Server, <-chan receivedHook) {
+	t.Helper()
+	received := make(chan receivedHook, 16)
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedHook{time.Now(), r.Header, body}
+		code := codes[len(codes)-1]
+		if n < len(codes) {
+			code = codes[n]
+		}
+		n++
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+// newTestWebhooks returns a started store in a temporary directory with a
+// short backoff.
+func newTestWebhooks(t *testing.T, maxAttempts int) *webhookStore {
+	t.Helper()
+	st, err := loadWebhooks(t.TempDir(), maxAttempts, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.backoff = 20 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	st.start(ctx)
+	return st
+}
+
+func testEvent(seq uint64) movieEvent {
+	return movieEvent{
+		Seq:     seq,
+		Type:    eventCreated,
+		MovieID: 1,
+		Movie:   &catalog.Movie{ID: 1, Title: "Sholay", Genre: "action", ReleaseDate: "15-08-1975"},
+		Time:    time.Now().UTC(),
+		Action:  "LoadMovies",
+	}
+}
+
+func receive(t *testing.T, received <-chan receivedHook) receivedHook {
+	t.Helper()
+	select {
+	case r := <-received:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery received")
+		return receivedHook{}
+	}
+}
+
+// waitDeliveries waits until n attempts of the webhook with id are logged
+// and returns them.
+func waitDeliveries(t *testing.T, st *webhookStore, id string, n int) []deliveryRecord {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		recs, err := st.deliveries(id, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recs) >= n {
+			return recs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d deliveries logged, want %d", len(recs), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	receiver, received := newReceiver(t, http.StatusOK)
+	st := newTestWebhooks(t, 3)
+	hook := webhook{ID: "h1", URL: receiver.URL, Secret: "s3cret"}
+	if err := st.register(hook); err != nil {
+		t.Fatal(err)
+	}
+	st.enqueue([]movieEvent{testEvent(1)})
+
+	r := receive(t, received)
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write(r.body)
+	if got, want := r.header.Get("X-Movie-Signature"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("signature %q, want %q", got, want)
+	}
+	if got := r.header.Get("X-Movie-Event"); got != eventCreated {
+		t.Errorf("event header %q, want %q", got, eventCreated)
+	}
+	var payload struct {
+		Seq   uint64 `json:"seq"`
+		Movie struct {
+			Title string `json:"title"`
+		} `json:"movie"`
+	}
+	if err := json.Unmarshal(r.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Seq != 1 || payload.Movie.Title != "Sholay" {
+		t.Errorf("payload %s", r.body)
+	}
+
+	fi, err := os.Stat(filepath.Join(st.dir, webhooksFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("%s has permissions %v, want 0600", webhooksFile, perm)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	receiver, received := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusNoContent)
+	st := newTestWebhooks(t, 5)
+	if err := st.register(webhook{ID: "h1", URL: receiver.URL, Secret: "s"}); err != nil {
+		t.Fatal(err)
+	}
+	st.enqueue([]movieEvent{testEvent(1)})
+
+	var times []time.Time
+	for i := 0; i < 3; i++ {
+		times = append(times, receive(t, received).time)
+	}
+	// The backoff doubles after every failed attempt.
+	for i, min := range []time.Duration{st.backoff, 2 * st.backoff} {
+		if gap := times[i+1].Sub(times[i]); gap < min {
+			t.Errorf("attempt %d came %v after the previous one, want at least %v", i+2, gap, min)
+		}
+	}
+
+	recs := waitDeliveries(t, st, "h1", 3)
+	want := []struct {
+		status string
+		code   int
+	}{
+		{deliveryRetrying, http.StatusInternalServerError},
+		{deliveryRetrying, http.StatusBadGateway},
+		{deliveryDelivered, http.StatusNoContent},
+	}
+	for i, w := range want {
+		r := recs[i]
+		if r.Attempt != i+1 || r.Status != w.status || r.HTTPStatus != w.code || r.Seq != 1 {
+			t.Errorf("delivery %d = %+v, want attempt %d, %s, %d", i, r, i+1, w.status, w.code)
+		}
+		if r.DeliveryID != recs[0].DeliveryID {
+			t.Errorf("delivery %d has id %s, want %s", i, r.DeliveryID, recs[0].DeliveryID)
+		}
+	}
+	if recs, _ := st.deliveries("h1", 1); len(recs) != 1 || recs[0].Status != deliveryDelivered {
+		t.Errorf("last delivery = %+v, want the delivered one", recs)
+	}
+	if d, _, _ := st.next("h1", time.Now().Add(time.Hour)); d != nil {
+		t.Errorf("delivery %s still queued", d.ID)
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	receiver, _ := newReceiver(t, http.StatusServiceUnavailable)
+	st := newTestWebhooks(t, 2)
+	if err := st.register(webhook{ID: "h1", URL: receiver.URL, Secret: "s"}); err != nil {
+		t.Fatal(err)
+	}
+	st.enqueue([]movieEvent{testEvent(7)})
+
+	recs := waitDeliveries(t, st, "h1", 2)
+	if recs[0].Status != deliveryRetrying || recs[1].Status != deliveryDead {
+		t.Errorf("statuses %s, %s, want %s, %s", recs[0].Status, recs[1].Status, deliveryRetrying, deliveryDead)
+	}
+
+	data, err := os.ReadFile(filepath.Join(st.dir, deadLettersFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d delivery
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != recs[0].DeliveryID || d.Seq != 7 || d.Attempts != 2 {
+		t.Errorf("dead letter %+v, want delivery %s of event 7 after 2 attempts", d, recs[0].DeliveryID)
+	}
+	var queue []delivery
+	if err := readJSONFile(filepath.Join(st.dir, queueFile), &queue); err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 0 {
+		t.Errorf("queue still holds %+v", queue)
+	}
+}
+
+func TestWebhookSlowReceiver(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast, received := newReceiver(t, http.StatusOK)
+
+	st := newTestWebhooks(t, 3)
+	for _, h := range []webhook{{ID: "slow", URL: slow.URL, Secret: "s"}, {ID: "fast", URL: fast.URL, Secret: "s"}} {
+		if err := st.register(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st.enqueue([]movieEvent{testEvent(1), testEvent(2)})
+
+	// Both events reach the fast receiver while the slow one holds the
+	// first.
+	for seq := uint64(1); seq <= 2; seq++ {
+		var payload struct {
+			Seq uint64 `json:"seq"`
+		}
+		if err := json.Unmarshal(receive(t, received).body, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.Seq != seq {
+			t.Errorf("received event %d, want %d", payload.Seq, seq)
+		}
+	}
+}
+
+func TestWebhookEnqueueDoesNotWrite(t *testing.T) {
+	receiver, received := newReceiver(t, http.StatusOK)
+	st, err := loadWebhooks(t.TempDir(), 3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.register(webhook{ID: "h1", URL: receiver.URL, Secret: "s"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Events wait for the queue writer, which start starts.
+	st.enqueue([]movieEvent{testEvent(1)})
+	if _, err := os.Stat(filepath.Join(st.dir, queueFile)); !os.IsNotExist(err) {
+		t.Errorf("%s written by enqueue: %v", queueFile, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st.start(ctx)
+	receive(t, received)
+}
+
+func TestListWebhookDeliveriesLimit(t *testing.T) {
+	s := &movieLibraryServer{webhooks: newTestWebhooks(t, 3)}
+	_, err := s.ListWebhookDeliveries(context.Background(), &pb.ListWebhookDeliveriesRequest{WebhookId: "h1", Limit: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("negative limit = %v, want InvalidArgument", err)
+	}
+	if _, err := s.ListWebhookDeliveries(context.Background(), &pb.ListWebhookDeliveriesRequest{WebhookId: "h1"}); err != nil {
+		t.Errorf("no limit = %v", err)
+	}
+}
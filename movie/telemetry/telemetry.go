@@ -0,0 +1,73 @@
+// Package telemetry configures OpenTelemetry tracing for the movie library
+// gateway and gRPC server.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// Exporter names accepted by Setup.
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterNone   = "none"
+)
+
+// Setup installs a global tracer provider for service and the W3C trace
+// context propagator used to carry spans across the gRPC hop. The OTLP
+// exporter honours the standard OTEL_EXPORTER_OTLP_* environment variables.
+// The returned function flushes pending spans and must be called on exit.
+func Setup(ctx context.Context, service, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var opt sdktrace.TracerProviderOption
+	switch exporter {
+	case ExporterOTLP, "":
+		exp, err := otlptracegrpc.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("create otlp exporter: %w", err)
+		}
+		opt = sdktrace.WithBatcher(exp)
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("create stdout exporter: %w", err)
+		}
+		opt = sdktrace.WithSyncer(exp)
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+
+	tp := sdktrace.NewTracerProvider(opt, sdktrace.WithResource(newResource(service)))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// NewProvider returns a tracer provider that sends every span synchronously
+// to exp. It is intended for tests, typically with an in-memory exporter
+// from go.opentelemetry.io/otel/sdk/trace/tracetest.
+func NewProvider(service string, exp sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exp),
+		sdktrace.WithResource(newResource(service)),
+	)
+}
+
+func newResource(service string) *resource.Resource {
+	return resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service))
+}
@@ -0,0 +1,34 @@
+package main
+
+import "os"
+
+// runConvert writes every movie of the input files in another format.
+func runConvert(args []string) int {
+	var files pathFlag
+	var fieldNames listFlag
+	fs := newFlagSet("convert", &files)
+	to := fs.String("to", "json", "target format: json, csv, table or xml")
+	fs.Var(&fieldNames, "fields", "fields to write, comma-separated (default all)")
+	withSource := fs.Bool("source", false, "add a source column with the file each movie came from")
+	fs.Parse(args)
+
+	fields, err := selectFields(fieldNames)
+	if err != nil {
+		fail(err)
+	}
+	if *withSource && !hasField(fields, "source") {
+		fields = append(fields, sourceField)
+	}
+	out, err := newRecordWriter(os.Stdout, *to, fields, "")
+	if err != nil {
+		fail(err)
+	}
+
+	if err := eachMovie(inputPaths(files), filter{}, out.Write); err != nil {
+		fail(err)
+	}
+	if err := out.Close(); err != nil {
+		fail(err)
+	}
+	return exitMatch
+}
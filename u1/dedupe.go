@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"movie/catalog"
+)
+
+// runDedupe lists likely duplicates in the input files, or with -merge
+// writes the files as one catalog with every group merged into its first
+// movie.
+func runDedupe(args []string) int {
+	var files pathFlag
+	fs := newFlagSet("dedupe", &files)
+	threshold := fs.Float64("threshold", catalog.DefaultDuplicateThreshold, "minimum title similarity, 0 to 1, of movies released on the same day")
+	output := fs.String("output", "text", "output format of the groups: text or json")
+	merge := fs.Bool("merge", false, "write the merged catalog as XML instead of listing groups")
+	fs.Parse(args)
+	if *threshold <= 0 || *threshold > 1 {
+		fail(fmt.Errorf("invalid -threshold %v, want above 0 and at most 1", *threshold))
+	}
+
+	movies, err := getMovies(inputPaths(files), filter{})
+	if err != nil {
+		fail(err)
+	}
+	groups := catalog.FindDuplicates(movies, *threshold)
+
+	if *merge {
+		if err := writeMerged(movies, groups); err != nil {
+			fail(err)
+		}
+		return exitMatch
+	}
+
+	switch *output {
+	case "json":
+		type entry struct {
+			Title       string `json:"title"`
+			Genre       string `json:"genre"`
+			ReleaseDate string `json:"releaseDate"`
+			Source      string `json:"source"`
+		}
+		out := [][]entry{}
+		for _, g := range groups {
+			var entries []entry
+			for _, i := range g {
+				m := movies[i]
+				entries = append(entries, entry{m.Title, m.Genre, m.ReleaseDate, m.Source})
+			}
+			out = append(out, entries)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(out)
+	case "text", "":
+		for n, g := range groups {
+			fmt.Printf("Group %d:\n", n+1)
+			for _, i := range g {
+				m := movies[i]
+				fmt.Printf("  %s (%s, %s) %s\n", m.Title, strings.TrimSpace(m.Genre), strings.TrimSpace(m.ReleaseDate), m.Source)
+			}
+		}
+		if len(groups) == 0 {
+			fmt.Println("No duplicates found.")
+		}
+	default:
+		err = fmt.Errorf("unknown output format %q, want text or json", *output)
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	if len(groups) == 0 {
+		return exitMatch
+	}
+	return exitNoMatch
+}
+
+// writeMerged writes movies as XML, replacing each group by the merge of
+// its movies at the position of the first one.
+func writeMerged(movies []catalog.Movie, groups [][]int) error {
+	merged := map[int]catalog.Movie{}
+	dropped := map[int]bool{}
+	for _, g := range groups {
+		var duplicates []catalog.Movie
+		for _, i := range g[1:] {
+			duplicates = append(duplicates, movies[i])
+			dropped[i] = true
+		}
+		merged[g[0]] = catalog.Merge(movies[g[0]], duplicates...)
+	}
+
+	out, err := newRecordWriter(os.Stdout, "xml", catalog.Fields, "")
+	if err != nil {
+		return err
+	}
+	for i, m := range movies {
+		if dropped[i] {
+			continue
+		}
+		if mm, ok := merged[i]; ok {
+			m = mm
+		}
+		if err := out.Write(m); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"movie/catalog"
+)
+
+type changedMovie struct {
+	Title   string                `json:"title"`
+	Changes []catalog.FieldChange `json:"changes"`
+}
+
+type movieDiff struct {
+	Added   []string       `json:"added"`
+	Removed []string       `json:"removed"`
+	Changed []changedMovie `json:"changed"`
+}
+
+func (d movieDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// runDiff compares two files: u1 diff [-output text|json] old.xml new.xml.
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	output := fs.String("output", "text", "output format: text or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: u1 diff [flags] old.xml new.xml")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError
+	}
+
+	oldMovies, err := getMovies([]string{fs.Arg(0)}, filter{})
+	if err != nil {
+		fail(err)
+	}
+	newMovies, err := getMovies([]string{fs.Arg(1)}, filter{})
+	if err != nil {
+		fail(err)
+	}
+	d := diffMovies(oldMovies, newMovies)
+
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(d)
+	case "text", "":
+		for _, t := range d.Removed {
+			fmt.Printf("- %s\n", t)
+		}
+		for _, t := range d.Added {
+			fmt.Printf("+ %s\n", t)
+		}
+		for _, c := range d.Changed {
+			for _, ch := range c.Changes {
+				fmt.Printf("~ %s: %s %q -> %q\n", c.Title, ch.Field, ch.Old, ch.New)
+			}
+		}
+	default:
+		err = fmt.Errorf("unknown output format %q, want text or json", *output)
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	if d.empty() {
+		return exitMatch
+	}
+	return exitNoMatch
+}
+
+// diffMovies matches movies by title, as catalog.DiffMovies does.
+func diffMovies(oldMovies, newMovies []catalog.Movie) movieDiff {
+	cd := catalog.DiffMovies(oldMovies, newMovies)
+	d := movieDiff{Added: []string{}, Removed: []string{}, Changed: []changedMovie{}}
+	for _, m := range cd.Removed {
+		d.Removed = append(d.Removed, m.Title)
+	}
+	for _, c := range cd.Changed {
+		d.Changed = append(d.Changed, changedMovie{c.Before.Title, c.Changes})
+	}
+	for _, m := range cd.Added {
+		d.Added = append(d.Added, m.Title)
+	}
+	return d
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"movie/catalog"
+)
+
+// listFlag is a flag that may be repeated and also accepts comma-separated
+// values, e.g. -genre crime -genre drama or -genre crime,drama.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// pathFlag is a flag that may be repeated. Unlike listFlag it takes each
+// value whole, so paths may contain commas.
+type pathFlag []string
+
+func (p *pathFlag) String() string {
+	return strings.Join(*p, " ")
+}
+
+func (p *pathFlag) Set(s string) error {
+	*p = append(*p, s)
+	return nil
+}
+
+// filter selects movies. A movie matches when it passes every criterion
+// that is set. Genres are resolved through tax, so aliases such as
+// "science fiction" match and a genre also matches the genres below it.
+type filter struct {
+	tax           *catalog.Taxonomy // nil means catalog.DefaultTaxonomy
+	genres        []string
+	allGenres     bool // require every genre instead of any of them
+	excludeGenres []string
+	title         *regexp.Regexp
+	from, to      time.Time // inclusive release date range, zero when open
+}
+
+// newFilter builds a filter from the command-line values. from and to are
+// DD-MM-YYYY dates and may be empty.
+func newFilter(genres, exclude []string, match, title, from, to string) (filter, error) {
+	f := filter{genres: genres, excludeGenres: exclude}
+
+	switch match {
+	case "any", "":
+	case "all":
+		f.allGenres = true
+	default:
+		return filter{}, fmt.Errorf("invalid -match %q, want any or all", match)
+	}
+
+	if title != "" {
+		re, err := regexp.Compile(title)
+		if err != nil {
+			return filter{}, fmt.Errorf("invalid -title: %w", err)
+		}
+		f.title = re
+	}
+
+	var err error
+	if f.from, err = parseDate(from); err != nil {
+		return filter{}, fmt.Errorf("invalid -from: %w", err)
+	}
+	if f.to, err = parseDate(to); err != nil {
+		return filter{}, fmt.Errorf("invalid -to: %w", err)
+	}
+	if !f.from.IsZero() && !f.to.IsZero() && f.to.Before(f.from) {
+		return filter{}, fmt.Errorf("-to %s is before -from %s", to, from)
+	}
+	return f, nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(catalog.ReleaseDateLayout, s)
+}
+
+func (f filter) taxonomy() *catalog.Taxonomy {
+	if f.tax == nil {
+		return defaultTaxonomy
+	}
+	return f.tax
+}
+
+var defaultTaxonomy = catalog.DefaultTaxonomy()
+
+// match reports whether m passes every criterion of f.
+func (f filter) match(m catalog.Movie) bool {
+	if len(f.genres) > 0 {
+		n := 0
+		for _, g := range f.genres {
+			if f.taxonomy().HasGenre(m, g) {
+				n++
+			}
+		}
+		if n == 0 || (f.allGenres && n < len(f.genres)) {
+			return false
+		}
+	}
+	for _, g := range f.excludeGenres {
+		if f.taxonomy().HasGenre(m, g) {
+			return false
+		}
+	}
+	if f.title != nil && !f.title.MatchString(m.Title) {
+		return false
+	}
+	if !f.from.IsZero() || !f.to.IsZero() {
+		released, err := m.Released()
+		if err != nil {
+			return false
+		}
+		if !f.from.IsZero() && released.Before(f.from) {
+			return false
+		}
+		if !f.to.IsZero() && released.After(f.to) {
+			return false
+		}
+	}
+	return true
+}
+
+// String describes f as a filter expression, e.g.
+// genre=crime|drama && !genre=sci-fi && title=~^Betty.
+func (f filter) String() string {
+	var terms []string
+	if len(f.genres) > 0 {
+		sep := "|"
+		if f.allGenres {
+			sep = "&"
+		}
+		terms = append(terms, "genre="+strings.Join(f.genres, sep))
+	}
+	for _, g := range f.excludeGenres {
+		terms = append(terms, "!genre="+g)
+	}
+	if f.title != nil {
+		terms = append(terms, "title=~"+f.title.String())
+	}
+	if !f.from.IsZero() {
+		terms = append(terms, "releaseDate>="+f.from.Format(catalog.ReleaseDateLayout))
+	}
+	if !f.to.IsZero() {
+		terms = append(terms, "releaseDate<="+f.to.Format(catalog.ReleaseDateLayout))
+	}
+	if len(terms) == 0 {
+		return "*"
+	}
+	return strings.Join(terms, " && ")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"movie/catalog"
+)
+
+func TestListFlags(t *testing.T) {
+	var genres listFlag
+	for _, v := range []string{"crime, drama", "", "sci-fi,"} {
+		genres.Set(v)
+	}
+	if want := (listFlag{"crime", "drama", "sci-fi"}); !reflect.DeepEqual(genres, want) {
+		t.Errorf("listFlag = %q, want %q", genres, want)
+	}
+
+	var files pathFlag
+	for _, v := range []string{"feeds/a,b.xml", "-"} {
+		files.Set(v)
+	}
+	if want := (pathFlag{"feeds/a,b.xml", "-"}); !reflect.DeepEqual(files, want) {
+		t.Errorf("pathFlag = %q, want %q", files, want)
+	}
+}
+
+func TestNewFilter(t *testing.T) {
+	tests := []struct {
+		genres, exclude        []string
+		match, title, from, to string
+		want, wantErr          string
+	}{
+		{want: "*"},
+		{genres: []string{"crime", "drama"}, want: "genre=crime|drama"},
+		{genres: []string{"crime", "drama"}, match: "all", want: "genre=crime&drama"},
+		{
+			genres: []string{"crime"}, exclude: []string{"sci-fi"}, title: "^Betty", from: "01-01-2023", to: "31-12-2023",
+			want: "genre=crime && !genre=sci-fi && title=~^Betty && releaseDate>=01-01-2023 && releaseDate<=31-12-2023",
+		},
+		{match: "some", wantErr: `invalid -match "some"`},
+		{title: "(", wantErr: "invalid -title"},
+		{from: "2023-01-01", wantErr: "invalid -from"},
+		{to: "32-01-2023", wantErr: "invalid -to"},
+		{from: "02-01-2023", to: "01-01-2023", wantErr: "-to 01-01-2023 is before -from 02-01-2023"},
+	}
+	for _, tt := range tests {
+		f, err := newFilter(tt.genres, tt.exclude, tt.match, tt.title, tt.from, tt.to)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newFilter(%+v) error = %v, want %q", tt, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newFilter(%+v): %v", tt, err)
+			continue
+		}
+		if got := f.String(); got != tt.want {
+			t.Errorf("newFilter(%+v) = %s, want %s", tt, got, tt.want)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	movies := []catalog.Movie{
+		{Title: "Betty-1", Genre: "crime", ReleaseDate: "01-10-2023"},
+		{Title: "Betty-2", Genre: "drama", Genres: []string{"drama", "crime"}, ReleaseDate: "15-03-2023"},
+		{Title: "Lagaan", Genre: "drama", Genres: []string{"drama", "sports"}, ReleaseDate: "15-06-2001"},
+		{Title: "Koi... Mil Gaya", Genre: "Science Fiction", ReleaseDate: "08-08-2003"},
+		{Title: "Kahaani", Genre: "crime-thriller", ReleaseDate: "bad date"},
+	}
+	tests := []struct {
+		genres, exclude        []string
+		match, title, from, to string
+		want                   []string
+	}{
+		{want: []string{"Betty-1", "Betty-2", "Lagaan", "Koi... Mil Gaya", "Kahaani"}},
+		{genres: []string{"Crime"}, want: []string{"Betty-1", "Betty-2"}},
+		{genres: []string{"crime", "sport"}, want: []string{"Betty-1", "Betty-2", "Lagaan"}},
+		{genres: []string{"crime", "drama"}, match: "all", want: []string{"Betty-2"}},
+		{genres: []string{"sci-fi"}, want: []string{"Koi... Mil Gaya"}},
+		// A genre matches the genres below it.
+		{genres: []string{"suspense"}, want: []string{"Kahaani"}},
+		{genres: []string{"drama"}, exclude: []string{"crime"}, want: []string{"Lagaan"}},
+		{title: "^Betty", want: []string{"Betty-1", "Betty-2"}},
+		{title: "^betty", want: nil},
+		// Movies without a valid date fail any date range.
+		{from: "01-01-2003", want: []string{"Betty-1", "Betty-2", "Koi... Mil Gaya"}},
+		{from: "01-01-2023", to: "30-09-2023", want: []string{"Betty-2"}},
+		{to: "08-08-2003", want: []string{"Lagaan", "Koi... Mil Gaya"}},
+	}
+	for _, tt := range tests {
+		f, err := newFilter(tt.genres, tt.exclude, tt.match, tt.title, tt.from, tt.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range movies {
+			if f.match(m) {
+				got = append(got, m.Title)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", f, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stdinPath is the -file value that reads from standard input.
+const stdinPath = "-"
+
+// expandInputs resolves -file values into the list of files to read. Glob
+// patterns are expanded, directories are searched recursively for *.xml
+// files and "-" stands for standard input. Files named more than once are
+// read once.
+func expandInputs(args []string) ([]string, error) {
+	var paths []string
+	seen := map[string]bool{}
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+
+	for _, arg := range args {
+		if arg == stdinPath {
+			add(arg)
+			continue
+		}
+
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(arg); err != nil {
+				return nil, fmt.Errorf("%s: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s: no files match", arg)
+			}
+		}
+
+		for _, m := range matches {
+			fi, err := os.Stat(m)
+			if err != nil {
+				return nil, err
+			}
+			if !fi.IsDir() {
+				add(m)
+				continue
+			}
+			err = filepath.WalkDir(m, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".xml") {
+					add(p)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return paths, nil
+}
+
+// openInput opens path for reading, or returns stdin for "-". The source
+// name is what the source column shows for movies from this input.
+func openInput(path string) (f *os.File, source string, err error) {
+	if path == stdinPath {
+		return os.Stdin, "stdin", nil
+	}
+	f, err = os.Open(path)
+	return f, path, err
+}
@@ -1,69 +1,180 @@
 package main
 
 import (
+	"bufio"
 	"encoding/xml"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
+
+	"movie/catalog"
 )
 
-type Movie struct {
-	Title       string `xml:"title,attr"`
-	Genre       string `xml:"genre,attr"`
-	ReleaseDate string `xml:"releaseDate,attr"`
+// getMovies returns the movies in the given files that match f, in file
+// order. "-" reads standard input.
+func getMovies(paths []string, f filter) ([]catalog.Movie, error) {
+	var matching []catalog.Movie
+	err := eachMovie(paths, f, func(movie catalog.Movie) error {
+		matching = append(matching, movie)
+		return nil
+	})
+	return matching, err
 }
 
-type Movies struct {
-	XMLName xml.Name `xml:"movies"`
-	Movies  []Movie  `xml:"movie"`
+// eachMovie calls fn for every movie in the given files that matches f, as
+// soon as it has been decoded.
+func eachMovie(paths []string, f filter, fn func(catalog.Movie) error) error {
+	for _, path := range paths {
+		err := streamMovies(path, func(movie catalog.Movie) error {
+			if !f.match(movie) {
+				return nil
+			}
+			return fn(movie)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func getMoviesByGenre(xmlFile string, genre string) ([]string, error) {
-	var movieList []string
-
-	xmlData, err := ioutil.ReadFile(xmlFile)
+// streamMovies decodes the <movie> elements of one file one at a time, so
+// memory use does not grow with the size of the file. Each movie gets its
+// Source set before fn is called.
+func streamMovies(path string, fn func(catalog.Movie) error) error {
+	in, source, err := openInput(path)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer in.Close()
 
-	var movies Movies
-	err = xml.Unmarshal(xmlData, &movies)
-	if err != nil {
-		return nil, err
-	}
+	d := xml.NewDecoder(bufio.NewReader(in))
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			if depth == 0 {
+				return nil
+			}
+			return fmt.Errorf("%s: %w", source, io.ErrUnexpectedEOF)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
 
-	for _, movie := range movies.Movies {
-		if movie.Genre == genre {
-			movieList = append(movieList, movie.Title)
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case depth == 0 && t.Name.Local != "movies":
+				return fmt.Errorf("%s: expected element type <movies> but have <%s>", source, t.Name.Local)
+			case depth == 1 && t.Name.Local == "movie":
+				var movie catalog.Movie
+				if err := d.DecodeElement(&movie, &t); err != nil {
+					return fmt.Errorf("%s: %w", source, err)
+				}
+				movie.Source = source
+				if err := fn(movie); err != nil {
+					return err
+				}
+				continue
+			case depth >= 1:
+				if err := d.Skip(); err != nil {
+					return fmt.Errorf("%s: %w", source, err)
+				}
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				// Anything after the root element is ignored, as with
+				// xml.Unmarshal.
+				return nil
+			}
 		}
 	}
+}
+
+// Exit codes, so scripts can tell an empty result from a failure.
+const (
+	exitMatch   = 0
+	exitNoMatch = 1
+	exitError   = 2
+)
 
-	return movieList, nil
+// command is a u1 subcommand. It receives the arguments after its name and
+// returns the process exit code.
+type command struct {
+	name, summary string
+	run           func(args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"query", "list movies matching filters (the default command)", runQuery},
+		{"stats", "count movies per genre, year and month", runStats},
+		{"validate", "check files for missing fields and bad dates", runValidate},
+		{"convert", "convert XML files to JSON, CSV or XML", runConvert},
+		{"diff", "show movies added, removed and changed between two files", runDiff},
+		{"dedupe", "find or merge likely duplicate movies", runDedupe},
+	}
 }
 
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	xmlFile := flag.String("file", "herd.xml", "a string")
-	genre := flag.String("genre", "crime", "a string")
+// run dispatches to the subcommand named by the first argument. Without one
+// the arguments are handled by query, as before subcommands existed.
+func run(args []string) int {
+	if len(args) > 0 {
+		if args[0] == "help" {
+			usage()
+			return exitMatch
+		}
+		for _, c := range commands {
+			if c.name == args[0] {
+				return c.run(args[1:])
+			}
+		}
+	}
+	return runQuery(args)
+}
 
-	flag.Parse()
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: u1 [command] [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun u1 <command> -h for the flags of a command.")
+}
 
-	//fmt.Println("file:", *xmlFile)
-	//fmt.Println("genre:", *genre)
+// newFlagSet returns the flag set of a subcommand with the -file flag that
+// all commands share.
+func newFlagSet(name string, files *pathFlag) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Var(files, "file", "XML file, glob or directory to read, repeatable; - reads stdin (default herd.xml)")
+	return fs
+}
 
-	movies, err := getMoviesByGenre(*xmlFile, *genre)
+// inputPaths expands the -file values, defaulting to herd.xml.
+func inputPaths(files pathFlag) []string {
+	if len(files) == 0 {
+		files = pathFlag{"herd.xml"}
+	}
+	paths, err := expandInputs(files)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		fail(err)
 	}
+	return paths
+}
 
-	if len(movies) > 0 {
-		fmt.Printf("Movies in the '%s' genre:\n", *genre)
-		for _, movie := range movies {
-			fmt.Println(movie)
-		}
-	} else {
-		fmt.Printf("No movies found in the '%s' genre.\n", *genre)
-	}
+// fail reports err on stderr and exits with exitError.
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(exitError)
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs u1 itself when the test binary is started by runU1.
+func TestMain(m *testing.M) {
+	if os.Getenv("U1_TEST_MAIN") == "1" {
+		main()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runU1 runs u1 with args in dir and returns its output and exit code.
+func runU1(t *testing.T, dir string, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "U1_TEST_MAIN=1")
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return string(out), exit.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), 0
+}
+
+const testCatalog = `<movies>
+<movie title="Betty-1" genre="crime" releaseDate="01-10-2023"/>
+<movie title="Lagaan" genre="drama" releaseDate="15-06-2001"/>
+</movies>`
+
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	// The comma is part of the file name, not a list separator.
+	if err := os.WriteFile(filepath.Join(dir, "a,b.xml"), []byte(testCatalog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.xml"), []byte("<movies><movie>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args    []string
+		code    int
+		wantOut string
+	}{
+		{[]string{"-file", "a,b.xml"}, exitMatch, "Betty-1"},
+		{[]string{"-file", "a,b.xml", "-genre", "drama", "-output", "csv"}, exitMatch, "Lagaan"},
+		{[]string{"query", "-file", "a,b.xml", "-title", "^L"}, exitMatch, "Lagaan"},
+		{[]string{"-file", "a,b.xml", "-genre", "western"}, exitNoMatch, "No movies found"},
+		{[]string{"-file", "a,b.xml", "-from", "01-01-2024"}, exitNoMatch, ""},
+		{[]string{"-file", "missing.xml"}, exitError, "Error:"},
+		{[]string{"-file", "broken.xml"}, exitError, "Error:"},
+		{[]string{"-file", "a,b.xml", "-match", "some"}, exitError, "invalid -match"},
+		{[]string{"-file", "a,b.xml", "-output", "yaml"}, exitError, "Error:"},
+		{[]string{"-no-such-flag"}, exitError, "flag provided but not defined"},
+		{[]string{"help"}, exitMatch, "Commands:"},
+	}
+	for _, tt := range tests {
+		out, code := runU1(t, dir, tt.args...)
+		if code != tt.code || !strings.Contains(out, tt.wantOut) {
+			t.Errorf("u1 %s = exit %d with %q, want exit %d with %q", strings.Join(tt.args, " "), code, out, tt.code, tt.wantOut)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"movie/catalog"
+)
+
+// sourceField is added by -source; it is not part of the default fields.
+var sourceField = catalog.Field{
+	Name: "source",
+	Get:  func(m catalog.Movie) interface{} { return m.Source },
+	Set:  func(m *catalog.Movie, s string) error { m.Source = s; return nil },
+}
+
+// selectFields resolves -fields names. No names selects every field.
+func selectFields(names []string) ([]catalog.Field, error) {
+	if len(names) == 0 {
+		return catalog.Fields, nil
+	}
+	var fields []catalog.Field
+	for _, name := range names {
+		f, ok := catalog.FieldByName(name)
+		if !ok && strings.EqualFold(name, sourceField.Name) {
+			f, ok = sourceField, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// recordWriter writes matching movies one at a time, so results can be
+// printed as they are found. Close must be called after the last movie.
+type recordWriter interface {
+	Write(catalog.Movie) error
+	Close() error
+}
+
+// newRecordWriter returns the writer for format. what describes the query
+// for the headings of the text format.
+func newRecordWriter(w io.Writer, format string, fields []catalog.Field, what string) (recordWriter, error) {
+	switch format {
+	case "text", "":
+		return &textWriter{w: w, fields: fields, what: what}, nil
+	case "json":
+		return &jsonWriter{w: w, fields: fields}, nil
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w), fields: fields}, nil
+	case "table":
+		return &tableWriter{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0), fields: fields}, nil
+	case "xml":
+		return &xmlWriter{w: w, enc: xml.NewEncoder(w), fields: fields}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, want text, json, csv, table or xml", format)
+}
+
+// textWriter keeps the original output: a heading followed by one line per
+// movie.
+type textWriter struct {
+	w      io.Writer
+	fields []catalog.Field
+	what   string
+	n      int
+}
+
+func (t *textWriter) Write(m catalog.Movie) error {
+	if t.n == 0 {
+		if _, err := fmt.Fprintf(t.w, "Movies %s:\n", t.what); err != nil {
+			return err
+		}
+	}
+	t.n++
+	values := make([]string, len(t.fields))
+	for i, f := range t.fields {
+		values[i] = f.Text(m)
+	}
+	_, err := fmt.Fprintln(t.w, strings.Join(values, ", "))
+	return err
+}
+
+func (t *textWriter) Close() error {
+	if t.n == 0 {
+		_, err := fmt.Fprintf(t.w, "No movies found %s.\n", t.what)
+		return err
+	}
+	return nil
+}
+
+// jsonWriter writes an array of objects whose keys follow the field order.
+type jsonWriter struct {
+	w      io.Writer
+	fields []catalog.Field
+	n      int
+}
+
+func (j *jsonWriter) Write(m catalog.Movie) error {
+	var b strings.Builder
+	if j.n == 0 {
+		b.WriteString("[\n  {")
+	} else {
+		b.WriteString(",\n  {")
+	}
+	j.n++
+	for i, f := range j.fields {
+		v := f.Get(m)
+		if s, ok := v.([]string); ok && s == nil {
+			v = []string{}
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%q: %s", f.Name, data)
+	}
+	b.WriteString("}")
+	_, err := io.WriteString(j.w, b.String())
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if j.n == 0 {
+		_, err := io.WriteString(j.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	fields []catalog.Field
+	header bool
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write(catalog.CSVHeader(c.fields))
+}
+
+func (c *csvWriter) Write(m catalog.Movie) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	if err := c.w.Write(catalog.CSVRecord(m, c.fields)); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// tableWriter aligns columns, so its rows only appear on Close.
+type tableWriter struct {
+	w      *tabwriter.Writer
+	fields []catalog.Field
+	header bool
+}
+
+func (t *tableWriter) writeRow(values []string) error {
+	_, err := fmt.Fprintln(t.w, strings.Join(values, "\t"))
+	return err
+}
+
+func (t *tableWriter) writeHeader() error {
+	if t.header {
+		return nil
+	}
+	t.header = true
+	names := make([]string, len(t.fields))
+	for i, f := range t.fields {
+		names[i] = strings.ToUpper(f.Name)
+	}
+	return t.writeRow(names)
+}
+
+func (t *tableWriter) Write(m catalog.Movie) error {
+	if err := t.writeHeader(); err != nil {
+		return err
+	}
+	row := make([]string, len(t.fields))
+	for i, f := range t.fields {
+		row[i] = f.Text(m)
+	}
+	return t.writeRow(row)
+}
+
+func (t *tableWriter) Close() error {
+	if err := t.writeHeader(); err != nil {
+		return err
+	}
+	return t.w.Flush()
+}
+
+// xmlWriter writes a <movies> document in the input format, leaving out the
+// fields that were not selected.
+type xmlWriter struct {
+	w       io.Writer
+	enc     *xml.Encoder
+	fields  []catalog.Field
+	started bool
+}
+
+var moviesStart = xml.StartElement{Name: xml.Name{Local: "movies"}}
+
+func (x *xmlWriter) start() error {
+	if x.started {
+		return nil
+	}
+	x.started = true
+	x.enc.Indent("", "  ")
+	return x.enc.EncodeToken(moviesStart)
+}
+
+func (x *xmlWriter) Write(m catalog.Movie) error {
+	if err := x.start(); err != nil {
+		return err
+	}
+	for _, f := range catalog.Fields {
+		if !hasField(x.fields, f.Name) {
+			f.Set(&m, "")
+		}
+	}
+	if err := x.enc.Encode(m); err != nil {
+		return err
+	}
+	return x.enc.Flush()
+}
+
+func (x *xmlWriter) Close() error {
+	if err := x.start(); err != nil {
+		return err
+	}
+	if err := x.enc.EncodeToken(moviesStart.End()); err != nil {
+		return err
+	}
+	if err := x.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(x.w, "\n")
+	return err
+}
+
+func hasField(fields []catalog.Field, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
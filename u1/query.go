@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"movie/catalog"
+)
+
+// runQuery lists the movies matching the filter flags.
+func runQuery(args []string) int {
+	var files pathFlag
+	var genres, excludeGenres, fieldNames listFlag
+
+	fs := newFlagSet("query", &files)
+	fs.Var(&genres, "genre", "genre to match, repeatable or comma-separated (default crime when no other filter is given)")
+	fs.Var(&excludeGenres, "exclude-genre", "genre to leave out, repeatable or comma-separated")
+	match := fs.String("match", "any", "with several -genre values, match movies having any or all of them")
+	title := fs.String("title", "", "regular expression the title must match")
+	from := fs.String("from", "", "earliest release date, DD-MM-YYYY")
+	to := fs.String("to", "", "latest release date, DD-MM-YYYY")
+	output := fs.String("output", "text", "output format: text, json, csv, table or xml")
+	fs.Var(&fieldNames, "fields", "fields to print, comma-separated (default title for text, all fields otherwise)")
+	withSource := fs.Bool("source", false, "add a source column with the file each movie came from")
+	server := fs.String("server", "", "query the gRPC server at host:port instead of reading files")
+	timeout := fs.Duration("timeout", 10*time.Second, "time limit for -server queries")
+
+	fs.Parse(args)
+
+	if len(genres) == 0 && len(excludeGenres) == 0 && *title == "" && *from == "" && *to == "" {
+		genres = listFlag{"crime"}
+	}
+	if len(fieldNames) == 0 && (*output == "text" || *output == "") {
+		fieldNames = listFlag{"title"}
+	}
+
+	f, err := newFilter(genres, excludeGenres, *match, *title, *from, *to)
+	if err != nil {
+		fail(err)
+	}
+	fields, err := selectFields(fieldNames)
+	if err != nil {
+		fail(err)
+	}
+	if *withSource && !hasField(fields, "source") {
+		fields = append(fields, sourceField)
+	}
+	if *server != "" && len(files) > 0 {
+		fail(errors.New("-server and -file cannot be combined"))
+	}
+
+	// A lone genre keeps the original wording.
+	what := fmt.Sprintf("matching %s", f)
+	if len(genres) == 1 && f.String() == "genre="+genres[0] {
+		what = fmt.Sprintf("in the '%s' genre", genres[0])
+	}
+	out, err := newRecordWriter(os.Stdout, *output, fields, what)
+	if err != nil {
+		fail(err)
+	}
+
+	matched := 0
+	write := func(movie catalog.Movie) error {
+		matched++
+		return out.Write(movie)
+	}
+	if *server != "" {
+		err = eachRemoteMovie(*server, *timeout, f, write)
+	} else {
+		err = eachMovie(inputPaths(files), f, write)
+	}
+	if err != nil {
+		fail(err)
+	}
+	if err := out.Close(); err != nil {
+		fail(err)
+	}
+
+	if matched == 0 {
+		return exitNoMatch
+	}
+	return exitMatch
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"movie/catalog"
+	pb "movie/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// request translates f into the equivalent server-side query.
+func (f filter) request() *pb.GetMovieDetailsRequest {
+	req := &pb.GetMovieDetailsRequest{
+		Genres:        f.genres,
+		AllGenres:     f.allGenres,
+		ExcludeGenres: f.excludeGenres,
+	}
+	if f.title != nil {
+		req.TitlePattern = f.title.String()
+	}
+	if !f.from.IsZero() {
+		req.ReleasedFrom = f.from.Format(catalog.ReleaseDateLayout)
+	}
+	if !f.to.IsZero() {
+		req.ReleasedTo = f.to.Format(catalog.ReleaseDateLayout)
+	}
+	return req
+}
+
+// eachRemoteMovie queries the MovieLibraryService at addr and calls fn for
+// every movie that matches f. The filter is applied again locally, so
+// servers that predate some of the filters still give the right result.
+func eachRemoteMovie(addr string, timeout time.Duration, f filter, fn func(catalog.Movie) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client := pb.NewMovieLibraryServiceClient(conn)
+	if f.tax, err = remoteTaxonomy(ctx, client); err != nil {
+		return err
+	}
+	resp, err := client.GetMovieDetails(ctx, f.request())
+	if err != nil {
+		return err
+	}
+	for _, m := range resp.Movies {
+		movie := catalog.FromProto(m)
+		movie.Source = addr
+		if !f.match(movie) {
+			continue
+		}
+		if err := fn(movie); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remoteTaxonomy returns the genre taxonomy of the server, so the local
+// filter resolves genres as the server does. Servers without ListGenres
+// get the default taxonomy.
+func remoteTaxonomy(ctx context.Context, client pb.MovieLibraryServiceClient) (*catalog.Taxonomy, error) {
+	resp, err := client.ListGenres(ctx, &pb.ListGenresRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return catalog.DefaultTaxonomy(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var genres []catalog.Genre
+	for _, g := range resp.Genres {
+		genres = append(genres, catalog.GenreFromProto(g))
+	}
+	return catalog.NewTaxonomy(genres)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"movie/catalog"
+)
+
+// unknownDate is the year and month key of movies without a valid release
+// date.
+const unknownDate = "unknown"
+
+// movieStats holds the counts reported by the stats command. Months are
+// keyed YYYY-MM.
+type movieStats struct {
+	Total  int            `json:"total"`
+	Genres map[string]int `json:"genres"`
+	Years  map[string]int `json:"years"`
+	Months map[string]int `json:"months"`
+}
+
+func (s *movieStats) add(m catalog.Movie) {
+	s.Total++
+
+	seen := map[string]bool{}
+	for _, g := range append([]string{m.Genre}, m.Genres...) {
+		g = strings.ToLower(strings.TrimSpace(g))
+		if g != "" && !seen[g] {
+			seen[g] = true
+			s.Genres[g]++
+		}
+	}
+
+	released, err := m.Released()
+	if err != nil {
+		s.Years[unknownDate]++
+		s.Months[unknownDate]++
+		return
+	}
+	s.Years[released.Format("2006")]++
+	s.Months[released.Format("2006-01")]++
+}
+
+// runStats counts movies per genre, release year and release month.
+func runStats(args []string) int {
+	var files pathFlag
+	fs := newFlagSet("stats", &files)
+	output := fs.String("output", "text", "output format: text or json")
+	fs.Parse(args)
+
+	st := movieStats{Genres: map[string]int{}, Years: map[string]int{}, Months: map[string]int{}}
+	err := eachMovie(inputPaths(files), filter{}, func(m catalog.Movie) error {
+		st.add(m)
+		return nil
+	})
+	if err != nil {
+		fail(err)
+	}
+
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(st)
+	case "text", "":
+		err = writeStats(os.Stdout, st)
+	default:
+		err = fmt.Errorf("unknown output format %q, want text or json", *output)
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	if st.Total == 0 {
+		return exitNoMatch
+	}
+	return exitMatch
+}
+
+// writeStats prints one table per breakdown. Genres are ordered by count,
+// years and months chronologically.
+func writeStats(w io.Writer, st movieStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Movies:\t%d\n", st.Total)
+
+	genres := sortedKeys(st.Genres)
+	sort.SliceStable(genres, func(i, j int) bool { return st.Genres[genres[i]] > st.Genres[genres[j]] })
+	for _, section := range []struct {
+		title  string
+		keys   []string
+		counts map[string]int
+	}{
+		{"GENRE", genres, st.Genres},
+		{"YEAR", sortedKeys(st.Years), st.Years},
+		{"MONTH", sortedKeys(st.Months), st.Months},
+	} {
+		fmt.Fprintf(tw, "\n%s\tCOUNT\n", section.title)
+		for _, k := range section.keys {
+			fmt.Fprintf(tw, "%s\t%d\n", k, section.counts[k])
+		}
+	}
+	return tw.Flush()
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
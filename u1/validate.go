@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"movie/catalog"
+)
+
+// problem is a validation finding at a position in an input file.
+type problem struct {
+	source string
+	catalog.Problem
+}
+
+func (p problem) String() string {
+	return fmt.Sprintf("%s:%s", p.source, p.Problem)
+}
+
+// runValidate checks the input files and lists every problem found.
+func runValidate(args []string) int {
+	var files pathFlag
+	fs := newFlagSet("validate", &files)
+	quiet := fs.Bool("q", false, "only set the exit code")
+	strict := fs.Bool("strict", false, "also report unknown elements and attributes and duplicate movies")
+	fs.Parse(args)
+
+	total := 0
+	for _, path := range inputPaths(files) {
+		problems, err := validateFile(path, *strict)
+		if err != nil {
+			fail(err)
+		}
+		total += len(problems)
+		if !*quiet {
+			for _, p := range problems {
+				fmt.Println(p)
+			}
+		}
+	}
+
+	if total > 0 {
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "%d problem(s) found\n", total)
+		}
+		return exitNoMatch
+	}
+	return exitMatch
+}
+
+// validateFile checks one input file with catalog.Validate.
+func validateFile(path string, strict bool) ([]problem, error) {
+	in, source, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	found, err := catalog.Validate(bufio.NewReader(in), strict)
+	if err != nil {
+		return nil, err
+	}
+	problems := make([]problem, len(found))
+	for i, p := range found {
+		problems[i] = problem{source, p}
+	}
+	return problems, nil
+}